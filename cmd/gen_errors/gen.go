@@ -14,10 +14,15 @@ import (
 const (
 	relativeSchemaPath      = "schema/error_schema.json"
 	relativeDefinitionsPath = "definitions"
+	relativeDomainsPath     = "domains.json"
+	relativeDomainsSchema   = "schema/domains_schema.json"
 	outputFile              = "errz_gen.go"
 	outputDir               = "docs"
 )
 
+// requiredLocales lists the locales every definition must translate.
+var requiredLocales = []string{"th"}
+
 func main() {
 	rootDir, err := projectRoot()
 	if err != nil {
@@ -29,6 +34,11 @@ func main() {
 		DefinitionsDir: filepath.Join(rootDir, relativeDefinitionsPath),
 		OutputPath:     filepath.Join(rootDir, outputFile),
 		OutputDocDir:   filepath.Join(rootDir, outputDir),
+
+		RequiredLocales: requiredLocales,
+
+		DomainsPath:       filepath.Join(rootDir, relativeDomainsPath),
+		DomainsSchemaPath: filepath.Join(rootDir, relativeDomainsSchema),
 	}
 
 	if err := gen.Run(); err != nil {
@@ -0,0 +1,168 @@
+package errz
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DiagnosticSeverity tells whether a Diagnostic fails generation. Every check
+// currently reports DiagnosticError.
+type DiagnosticSeverity string
+
+// DiagnosticError fails generation.
+const DiagnosticError DiagnosticSeverity = "error"
+
+// Rules identify the check that produced a Diagnostic. Schema violations use
+// "schema/" followed by the gojsonschema error type, e.g. "schema/required".
+// Failures outside any check, such as an unreadable directory, have no rule.
+const (
+	RuleRead           = "read"            // a file could not be read
+	RuleSyntax         = "syntax"          // a file is not valid JSON or has the wrong shape
+	RuleEmptyFile      = "empty-file"      // a file declares nothing
+	RuleDuplicateKey   = "duplicate-key"   // a key is declared twice in one file
+	RuleDuplicateCode  = "duplicate-code"  // a code is declared in two files
+	RuleRetiredCode    = "retired-code"    // a code is retired twice or reused
+	RuleKeyMismatch    = "key-mismatch"    // a key differs from its code field
+	RulePrefixDomain   = "prefix-domain"   // prefixes and domains do not map one to one
+	RuleDuplicateMsg   = "duplicate-msg"   // two active codes share a msg
+	RuleParam          = "param"           // a param is invalid or declared twice
+	RulePlaceholder    = "placeholder"     // a placeholder is not declared in params
+	RuleHTTPStatus     = "http-status"     // http_status is not a known status
+	RuleTranslation    = "translation"     // a translation is missing or declared twice
+	RuleRetry          = "retry"           // retry hints are inconsistent
+	RuleDeprecation    = "deprecation"     // replaced_by is inconsistent
+	RuleDomainRegistry = "domain-registry" // a code does not match the domain registry
+)
+
+// Position is a 1-based line and byte column in a file.
+type Position struct {
+	Line   int
+	Column int
+}
+
+// IsValid reports whether p points into a file.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns "line:column".
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Diagnostic is a problem found while validating or loading definitions.
+type Diagnostic struct {
+	File     string             // file the problem is in, if any
+	Code     string             // error code the problem is about, if any
+	Field    string             // dotted path of the offending member, below Code if set, e.g. "params.0.type"
+	Rule     string             // check that failed, one of the Rule constants or "schema/..."
+	Message  string             // human-readable description, without the code
+	Position Position           // position of the offending member in File, if known
+	Severity DiagnosticSeverity // currently always DiagnosticError
+
+	excerpt string // source line and caret under Position
+	err     error  // underlying error, if any
+}
+
+// Error renders d compiler-style as "file:line:column: code: message",
+// followed by the source line and a caret under the column.
+func (d *Diagnostic) Error() string {
+	msg := d.Message
+	if d.Code != "" {
+		msg = d.Code + ": " + msg
+	}
+	switch {
+	case d.File == "":
+		return msg
+	case !d.Position.IsValid():
+		return d.File + ": " + msg
+	}
+	s := fmt.Sprintf("%s:%s: %s", d.File, d.Position, msg)
+	if d.excerpt != "" {
+		s += "\n" + d.excerpt
+	}
+	return s
+}
+
+// Unwrap returns the error that caused d, if any.
+func (d *Diagnostic) Unwrap() error {
+	return d.err
+}
+
+// Diagnostics is every problem found by a run, sorted by file, position and
+// message so that the problems of each file are grouped together.
+type Diagnostics []*Diagnostic
+
+// newDiagnostics collects errs into Diagnostics. Joined errors are flattened,
+// wrappers around diagnostics are dropped in favor of the diagnostics, and
+// any other error becomes a Diagnostic without a rule.
+func newDiagnostics(errs ...error) Diagnostics {
+	var ds Diagnostics
+	var collect func(err error)
+	collect = func(err error) {
+		var d *Diagnostic
+		switch e := err.(type) {
+		case nil:
+		case *Diagnostic:
+			ds = append(ds, e)
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				collect(err)
+			}
+		default:
+			if inner := errors.Unwrap(err); inner != nil && errors.As(inner, &d) {
+				collect(inner)
+				return
+			}
+			ds = append(ds, &Diagnostic{Message: err.Error(), Severity: DiagnosticError, err: err})
+		}
+	}
+	for _, err := range errs {
+		collect(err)
+	}
+
+	slices.SortStableFunc(ds, func(a, b *Diagnostic) int {
+		return cmp.Or(
+			strings.Compare(a.File, b.File),
+			cmp.Compare(a.Position.Line, b.Position.Line),
+			cmp.Compare(a.Position.Column, b.Position.Column),
+			strings.Compare(a.Message, b.Message),
+		)
+	})
+
+	return ds
+}
+
+// HasErrors reports whether any diagnostic has DiagnosticError severity.
+func (ds Diagnostics) HasErrors() bool {
+	return slices.ContainsFunc(ds, func(d *Diagnostic) bool {
+		return d.Severity == DiagnosticError
+	})
+}
+
+// Error lists every diagnostic, with a blank line between files.
+func (ds Diagnostics) Error() string {
+	var b strings.Builder
+	for i, d := range ds {
+		if i > 0 {
+			b.WriteByte('\n')
+			if d.File != ds[i-1].File {
+				b.WriteByte('\n')
+			}
+		}
+		b.WriteString(d.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the diagnostics, so errors.Is and errors.As search all of them.
+func (ds Diagnostics) Unwrap() []error {
+	errs := make([]error, len(ds))
+	for i, d := range ds {
+		errs[i] = d
+	}
+	return errs
+}
@@ -0,0 +1,41 @@
+package errz
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostic_Error(t *testing.T) {
+	assert.EqualError(t, &Diagnostic{Message: "bad"}, "bad")
+	assert.EqualError(t, &Diagnostic{File: "a.json", Message: "bad"}, "a.json: bad")
+	assert.EqualError(t, &Diagnostic{File: "a.json", Position: Position{Line: 2, Column: 3}, Message: "bad"}, "a.json:2:3: bad")
+	assert.EqualError(t, &Diagnostic{Code: "PM0001", Message: "bad"}, "PM0001: bad")
+	assert.EqualError(t, &Diagnostic{File: "a.json", Code: "PM0001", Position: Position{Line: 2, Column: 3}, Message: "bad"}, "a.json:2:3: PM0001: bad")
+}
+
+func TestNewDiagnostics(t *testing.T) {
+	assert.Empty(t, newDiagnostics(nil, errors.Join(nil, nil)))
+
+	sentinel := errors.New("sentinel")
+	b := &Diagnostic{File: "b.json", Position: Position{Line: 1, Column: 1}, Message: "b", Severity: DiagnosticError}
+	a2 := &Diagnostic{File: "a.json", Position: Position{Line: 9, Column: 1}, Message: "a2", Severity: DiagnosticError}
+	a1 := &Diagnostic{File: "a.json", Position: Position{Line: 2, Column: 3}, Message: "a1", Severity: DiagnosticError}
+	ds := newDiagnostics(errors.Join(b, a2), nil, fmt.Errorf("wrapped: %w", a1), sentinel)
+	require.Len(t, ds, 4)
+
+	assert.Equal(t, "sentinel\n\na.json:2:3: a1\na.json:9:1: a2\n\nb.json:1:1: b", ds.Error())
+	assert.Same(t, a1, ds[1])
+	assert.Equal(t, DiagnosticError, ds[0].Severity)
+	assert.ErrorIs(t, ds, sentinel)
+	assert.ErrorIs(t, ds, a2)
+	assert.True(t, ds.HasErrors())
+	assert.False(t, Diagnostics(nil).HasErrors())
+
+	var d *Diagnostic
+	require.ErrorAs(t, error(ds), &d)
+	assert.Equal(t, "sentinel", d.Message)
+}
@@ -1,7 +1,23 @@
 // Code generated by gen_errors/gen.go; DO NOT EDIT.
 package errz
 
-import "fmt"
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 // Error represents a centralized error definition.
 type Error struct {
@@ -9,11 +25,179 @@ type Error struct {
 	Code        string
 	Msg         string
 	Cause       string
+
+	httpStatus   int               // HTTP status declared in the definition
+	severity     Severity          // alerting severity declared in the definition
+	category     Category          // fault category declared in the definition
+	retryable    bool              // the failed operation may be retried
+	retryPolicy  RetryPolicy       // backoff hints for retryable errors
+	sensitive    bool              // msg must not reach external clients
+	translations map[string]string // msg keyed by lowercase BCP 47 tag
+	fields       map[string]any    // context attached by With; never mutated in place
+	err          error             // underlying error attached by Wrap
+	stack        []uintptr         // captured by Wrap and New<CODE> when enabled
 }
 
 func (e *Error) Error() string {
-	return fmt.Sprintf("[%s][%s] msg: %s | cause: %s",
+	msg := fmt.Sprintf("[%s][%s] msg: %s | cause: %s",
 		e.Domain, e.Code, e.Msg, e.Cause)
+	if e.err != nil {
+		msg += ": " + e.err.Error()
+	}
+	return msg
+}
+
+// Wrap returns a copy of e carrying err as its underlying error.
+func (e *Error) Wrap(err error) *Error {
+	return e.wrap(context.Background(), err)
+}
+
+// WrapContext is like Wrap but passes ctx to observers, so they can reach
+// the trace span of the operation that failed.
+func (e *Error) WrapContext(ctx context.Context, err error) *Error {
+	return e.wrap(ctx, err)
+}
+
+// WithCause is an alias for Wrap.
+func (e *Error) WithCause(err error) *Error {
+	return e.wrap(context.Background(), err)
+}
+
+// wrap is called directly by the exported wrapping methods so that the
+// captured stack starts at their caller.
+func (e *Error) wrap(ctx context.Context, err error) *Error {
+	c := *e
+	c.err = err
+	c.stack = callers()
+	notify(ctx, EventCreated, &c)
+	return &c
+}
+
+// With returns a copy of e with key set to value in its fields.
+func (e *Error) With(key string, value any) *Error {
+	c := *e
+	c.fields = make(map[string]any, len(e.fields)+1)
+	maps.Copy(c.fields, e.fields)
+	c.fields[key] = value
+	return &c
+}
+
+// Fields returns a copy of the fields attached with With, or nil.
+func (e *Error) Fields() map[string]any {
+	return maps.Clone(e.fields)
+}
+
+// Unwrap returns the underlying error attached by Wrap, if any.
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// HTTPStatus returns the HTTP status declared for the error. Without one it
+// is derived from the category, defaulting to 500.
+func (e *Error) HTTPStatus() int {
+	if e.httpStatus != 0 {
+		return e.httpStatus
+	}
+	switch e.category {
+	case CategoryClient:
+		return 400
+	case CategoryBusiness:
+		return 422
+	case CategoryDependency:
+		return 502
+	default:
+		return 500
+	}
+}
+
+// Localize returns the message translated into the first of tags that has a
+// translation. Each tag falls back to its parent ("th-TH" to "th") before the
+// next tag is tried, and Msg is returned when nothing matches. Sensitive
+// errors always return RedactedMessage.
+func (e *Error) Localize(tags ...string) string {
+	if e.sensitive {
+		return RedactedMessage
+	}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+		for tag != "" {
+			if msg, ok := e.translations[tag]; ok {
+				return msg
+			}
+			i := strings.LastIndexByte(tag, '-')
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return e.Msg
+}
+
+// withParams returns a copy of e with {name} placeholders in Msg and Cause
+// replaced by the given name/value pairs. Pairs used by Msg or a translation
+// are also attached as fields; pairs used only by Cause are not, since fields
+// reach clients and the cause is for operators only.
+// Observers are notified with ctx. It is only called by the generated
+// New<CODE> constructors.
+func (e *Error) withParams(ctx context.Context, pairs ...any) *Error {
+	c := *e
+	c.stack = callers()
+	c.fields = make(map[string]any, len(e.fields)+len(pairs)/2)
+	maps.Copy(c.fields, e.fields)
+	oldnew := make([]string, 0, len(pairs))
+	for i := 0; i+1 < len(pairs); i += 2 {
+		name := fmt.Sprint(pairs[i])
+		oldnew = append(oldnew, "{"+name+"}", fmt.Sprint(pairs[i+1]))
+		if e.isPublicParam(name) {
+			c.fields[name] = pairs[i+1]
+		}
+	}
+	r := strings.NewReplacer(oldnew...)
+	c.Msg = r.Replace(c.Msg)
+	c.Cause = r.Replace(c.Cause)
+	if c.translations != nil {
+		translations := make(map[string]string, len(c.translations))
+		for tag, msg := range c.translations {
+			translations[tag] = r.Replace(msg)
+		}
+		c.translations = translations
+	}
+	notify(ctx, EventCreated, &c)
+	return &c
+}
+
+// isPublicParam reports whether the {name} placeholder appears in Msg or in a
+// translation, i.e. in text that may be shown to clients.
+func (e *Error) isPublicParam(name string) bool {
+	placeholder := "{" + name + "}"
+	if strings.Contains(e.Msg, placeholder) {
+		return true
+	}
+	for _, msg := range e.translations {
+		if strings.Contains(msg, placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// Is reports whether target is an *Error with the same code.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok || e == nil || t == nil {
+		return false
+	}
+	return e.Code == t.Code
+}
+
+// CodeOf returns the code of the first *Error in err's chain.
+func CodeOf(err error) (string, bool) {
+	var e *Error
+	if !errors.As(err, &e) || e == nil {
+		return "", false
+	}
+	return e.Code, true
 }
 
 var (
@@ -22,36 +206,687 @@ var (
 		Code: "AU0001",
 		Msg: "invalid credentials",
 		Cause: "username or password incorrect",
+		httpStatus: 401,
+		severity: SeverityWarning,
+		category: CategoryClient,
+		translations: map[string]string{
+			"th": "ข้อมูลเข้าสู่ระบบไม่ถูกต้อง",
+		},
 	}
 	CM0000 = &Error{
 		Domain: "common",
 		Code: "CM0000",
 		Msg: "success",
 		Cause: "operation completed successfully",
+		httpStatus: 200,
+		severity: SeverityInfo,
+		category: CategoryBusiness,
+		translations: map[string]string{
+			"th": "สำเร็จ",
+		},
 	}
 	CM0400 = &Error{
 		Domain: "common",
 		Code: "CM0400",
 		Msg: "bad request",
 		Cause: "invalid input or malformed request",
+		httpStatus: 400,
+		severity: SeverityWarning,
+		category: CategoryClient,
+		translations: map[string]string{
+			"th": "คำขอไม่ถูกต้อง",
+		},
 	}
 	CM0500 = &Error{
 		Domain: "common",
 		Code: "CM0500",
 		Msg: "internal server error",
 		Cause: "unexpected server-side error",
+		httpStatus: 500,
+		severity: SeverityError,
+		category: CategoryServer,
+		translations: map[string]string{
+			"th": "เกิดข้อผิดพลาดภายในเซิร์ฟเวอร์",
+		},
 	}
 	PM0001 = &Error{
 		Domain: "payment",
 		Code: "PM0001",
 		Msg: "insufficient balance",
 		Cause: "user has not enough balance",
+		httpStatus: 402,
+		severity: SeverityWarning,
+		category: CategoryBusiness,
+		translations: map[string]string{
+			"th": "ยอดเงินคงเหลือไม่เพียงพอ",
+		},
 	}
 	PM0002 = &Error{
 		Domain: "payment",
 		Code: "PM0002",
 		Msg: "payment gateway timeout",
 		Cause: "no response from payment gateway",
+		httpStatus: 504,
+		severity: SeverityError,
+		category: CategoryDependency,
+		retryable: true,
+		retryPolicy: RetryPolicy{MaxAttempts: 3, InitialBackoff: 200 * time.Millisecond, MaxBackoff: 2000 * time.Millisecond},
+		translations: map[string]string{
+			"th": "เกตเวย์ชำระเงินหมดเวลาตอบสนอง",
+		},
+	}
+)
+
+// registry lists every error in code order.
+var registry = []*Error{
+	AU0001,
+	CM0000,
+	CM0400,
+	CM0500,
+	PM0001,
+	PM0002,
+}
+
+var byCode = map[string]*Error{
+	"AU0001": AU0001,
+	"CM0000": CM0000,
+	"CM0400": CM0400,
+	"CM0500": CM0500,
+	"PM0001": PM0001,
+	"PM0002": PM0002,
+}
+
+// domains lists every domain in alphabetical order.
+var domains = []string{
+	"auth",
+	"common",
+	"payment",
+}
+
+// Lookup returns the error variable defined for code.
+func Lookup(code string) (*Error, bool) {
+	e, ok := byCode[code]
+	return e, ok
+}
+
+// All returns every error variable in code order.
+func All() iter.Seq[*Error] {
+	return slices.Values(registry)
+}
+
+// Domains returns every domain in alphabetical order.
+func Domains() []string {
+	return slices.Clone(domains)
+}
+
+// ByDomain returns the error variables of domain in code order.
+func ByDomain(domain string) iter.Seq[*Error] {
+	return func(yield func(*Error) bool) {
+		for _, e := range registry {
+			if e.Domain == domain && !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// RedactedMessage replaces the message of sensitive errors sent to external
+// clients.
+var RedactedMessage = "an unexpected error occurred"
+
+// PublicMessage returns the message that may be shown to external clients.
+func (e *Error) PublicMessage() string {
+	if e.sensitive {
+		return RedactedMessage
+	}
+	return e.Msg
+}
+
+// InternalDetail returns the operator-facing cause followed by the wrapped
+// error, if any. It must not be sent to external clients.
+func (e *Error) InternalDetail() string {
+	if e.err == nil {
+		return e.Cause
+	}
+	return e.Cause + ": " + e.err.Error()
+}
+
+// Redact returns a copy of the first *Error in err's chain that is safe to
+// serialize to external clients: the cause and wrapped error are dropped and
+// sensitive errors have their message replaced by RedactedMessage and their
+// fields removed. It returns nil when err carries no *Error.
+func Redact(err error) *Error {
+	var e *Error
+	if !errors.As(err, &e) || e == nil {
+		return nil
+	}
+	c := *e
+	c.Msg = e.PublicMessage()
+	c.Cause = ""
+	c.err = nil
+	c.stack = nil
+	if e.sensitive {
+		c.translations = nil
+		c.fields = nil
+	}
+	return &c
+}
+
+// LogValue implements slog.LogValuer, logging the error as a group of its
+// fields so log queries can filter by code.
+func (e *Error) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("code", e.Code),
+		slog.String("domain", e.Domain),
+		slog.String("msg", e.Msg),
+		slog.String("cause", e.Cause),
+		slog.String("severity", string(e.Severity())),
+		slog.String("category", string(e.Category())),
+	}
+	if e.err != nil {
+		attrs = append(attrs, slog.String("error", e.err.Error()))
+	}
+	if len(e.fields) > 0 {
+		fields := make([]any, 0, len(e.fields))
+		for _, k := range slices.Sorted(maps.Keys(e.fields)) {
+			fields = append(fields, slog.Any(k, e.fields[k]))
+		}
+		attrs = append(attrs, slog.Group("fields", fields...))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// Severity is the alerting severity of an error.
+type Severity string
+
+const (
+	SeverityDebug    Severity = "debug"
+	SeverityInfo     Severity = "info"
+	SeverityWarning  Severity = "warning"
+	SeverityError    Severity = "error"
+	SeverityCritical Severity = "critical"
+)
+
+// Level returns the slog level matching s. Critical maps above slog.LevelError.
+func (s Severity) Level() slog.Level {
+	switch s {
+	case SeverityDebug:
+		return slog.LevelDebug
+	case SeverityInfo:
+		return slog.LevelInfo
+	case SeverityWarning:
+		return slog.LevelWarn
+	case SeverityCritical:
+		return slog.LevelError + 4
+	default:
+		return slog.LevelError
+	}
+}
+
+// Category classifies who is at fault for an error.
+type Category string
+
+const (
+	CategoryClient     Category = "client"
+	CategoryServer     Category = "server"
+	CategoryDependency Category = "dependency"
+	CategoryBusiness   Category = "business"
+)
+
+// Severity returns the declared severity, or SeverityError when undeclared.
+func (e *Error) Severity() Severity {
+	if e.severity == "" {
+		return SeverityError
+	}
+	return e.severity
+}
+
+// Category returns the declared category, or CategoryServer when undeclared.
+func (e *Error) Category() Category {
+	if e.category == "" {
+		return CategoryServer
+	}
+	return e.category
+}
+
+// SeverityOf returns the severity of the first *Error in err's chain, or
+// SeverityError when there is none.
+func SeverityOf(err error) Severity {
+	var e *Error
+	if !errors.As(err, &e) || e == nil {
+		return SeverityError
+	}
+	return e.Severity()
+}
+
+// CategoryOf returns the category of the first *Error in err's chain, or
+// CategoryServer when there is none.
+func CategoryOf(err error) Category {
+	var e *Error
+	if !errors.As(err, &e) || e == nil {
+		return CategoryServer
+	}
+	return e.Category()
+}
+
+// IsClientError reports whether CategoryOf(err) is CategoryClient.
+func IsClientError(err error) bool {
+	return CategoryOf(err) == CategoryClient
+}
+
+// IsServerError reports whether CategoryOf(err) is CategoryServer.
+func IsServerError(err error) bool {
+	return CategoryOf(err) == CategoryServer
+}
+
+// IsDependencyError reports whether CategoryOf(err) is CategoryDependency.
+func IsDependencyError(err error) bool {
+	return CategoryOf(err) == CategoryDependency
+}
+
+// IsBusinessError reports whether CategoryOf(err) is CategoryBusiness.
+func IsBusinessError(err error) bool {
+	return CategoryOf(err) == CategoryBusiness
+}
+
+// RetryPolicy holds the backoff hints declared for a retryable error. Zero
+// fields defer to the RetryOptions passed to Retry.
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Retryable reports whether the definition allows retrying the operation.
+func (e *Error) Retryable() bool {
+	return e.retryable
+}
+
+// RetryPolicy returns the backoff hints declared for the error.
+func (e *Error) RetryPolicy() RetryPolicy {
+	return e.retryPolicy
+}
+
+// IsRetryable reports whether the first *Error in err's chain is retryable.
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e != nil && e.retryable
+}
+
+// RetryOptions configures Retry. Zero fields use the defaults noted below.
+type RetryOptions struct {
+	MaxAttempts    int           // used when the error declares none; default 3
+	InitialBackoff time.Duration // used when the error declares none; default 100ms
+	MaxBackoff     time.Duration // used when the error declares none; default 5s
+}
+
+// Retry calls fn until it succeeds, returns an error that is not retryable,
+// or reaches the max attempts of the code it last failed with. The backoff
+// doubles after every attempt. If ctx is done while waiting, Retry returns
+// ctx.Err() joined with the last error.
+func Retry(ctx context.Context, fn func(context.Context) error, opts RetryOptions) error {
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		var e *Error
+		if !errors.As(err, &e) || e == nil || !e.retryable {
+			return err
+		}
+
+		policy := e.retryPolicy.withDefaults(opts)
+		if attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// withDefaults fills the zero fields of p from opts and the package defaults.
+func (p RetryPolicy) withDefaults(opts RetryOptions) RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = cmp.Or(opts.MaxAttempts, 3)
+	}
+	if p.InitialBackoff == 0 {
+		p.InitialBackoff = cmp.Or(opts.InitialBackoff, 100*time.Millisecond)
+	}
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = cmp.Or(opts.MaxBackoff, 5*time.Second)
+	}
+	return p
+}
+
+// backoff returns the wait after the given attempt, doubling from
+// InitialBackoff and capped at MaxBackoff.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 1; i < attempt && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// wireError is the JSON wire shape of an Error:
+//
+//	{"code": "PM0002", "domain": "payment", "msg": "...", "fields": {...}, "wrapped": {...}}
+//
+// wrapped holds the next *Error in the wrap chain, or {"msg": "..."} for any
+// other wrapped error. Only code is required.
+type wireError struct {
+	Code    string         `json:"code,omitempty"`
+	Domain  string         `json:"domain,omitempty"`
+	Msg     string         `json:"msg,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Wrapped *wireError     `json:"wrapped,omitempty"`
+}
+
+// MarshalJSON encodes e in the wire shape documented on wireError.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.wire())
+}
+
+func (e *Error) wire() *wireError {
+	w := &wireError{
+		Code:   e.Code,
+		Domain: e.Domain,
+		Msg:    e.Msg,
+		Fields: e.fields,
+	}
+	if e.err != nil {
+		var next *Error
+		if errors.As(e.err, &next) && next != nil {
+			w.Wrapped = next.wire()
+		} else {
+			w.Wrapped = &wireError{Msg: e.err.Error()}
+		}
+	}
+	return w
+}
+
+// UnmarshalJSON decodes the wire shape documented on wireError. Known codes
+// resolve to a copy of their generated variable; unknown codes from newer
+// producers are kept as decoded.
+func (e *Error) UnmarshalJSON(data []byte) error {
+	var w wireError
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	if w.Code == "" {
+		return errors.New("errz: decode error: missing code")
+	}
+	*e = *w.decode()
+	return nil
+}
+
+func (w *wireError) decode() *Error {
+	var e Error
+	if known, ok := Lookup(w.Code); ok {
+		e = *known
+	} else {
+		e = Error{Domain: w.Domain, Code: w.Code}
+	}
+	if w.Msg != "" {
+		e.Msg = w.Msg
+	}
+	e.fields = w.Fields
+	switch {
+	case w.Wrapped == nil:
+	case w.Wrapped.Code != "":
+		e.err = w.Wrapped.decode()
+	default:
+		e.err = errors.New(w.Wrapped.Msg)
+	}
+	return &e
+}
+
+// maxStackDepth bounds the number of frames captured per error.
+const maxStackDepth = 32
+
+var stackTraces atomic.Bool
+
+// EnableStackTraces turns stack capture in Wrap, WithCause and the New<CODE>
+// constructors on or off. It is off by default, in which case nothing is
+// captured or allocated.
+func EnableStackTraces(enabled bool) {
+	stackTraces.Store(enabled)
+}
+
+// callers returns the stack of the caller of the exported function that
+// called callers through one unexported helper, or nil when disabled.
+func callers() []uintptr {
+	if !stackTraces.Load() {
+		return nil
+	}
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(4, pcs[:])
+	return slices.Clone(pcs[:n])
+}
+
+// StackTrace returns the frames captured when e was created by Wrap or a
+// New<CODE> constructor, or nil when stack traces were disabled.
+func (e *Error) StackTrace() []runtime.Frame {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(e.stack)
+	var out []runtime.Frame
+	for {
+		f, more := frames.Next()
+		out = append(out, f)
+		if !more {
+			return out
+		}
 	}
+}
+
+// Format implements fmt.Formatter. %s and %v print Error(), %q prints it
+// quoted, and %+v also prints the captured stack trace, one frame per line.
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		io.WriteString(s, e.Error())
+		if s.Flag('+') {
+			for _, f := range e.StackTrace() {
+				fmt.Fprintf(s, "\n%s\n\t%s:%d", f.Function, f.File, f.Line)
+			}
+		}
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprintf(s, "%%!%c(*errz.Error=%s)", verb, e.Error())
+	}
+}
+
+// Event tells an Observer why it is notified.
+type Event int
+
+const (
+	// EventCreated is sent by Wrap, WithCause and the New<CODE> constructors,
+	// with the context given to WrapContext and New<CODE>Context.
+	EventCreated Event = iota + 1
+	// EventRecorded is sent by Record and RecordContext.
+	EventRecorded
 )
 
+// Observer is notified whenever an error is created or recorded, e.g. to add
+// its code to the trace span found in ctx.
+//
+// Observers are called synchronously, on the goroutine that created or
+// recorded the error, in the order they were added. They may be called
+// concurrently from many goroutines, must not modify the error and should
+// return quickly. Adding or removing an observer is safe at any time, and
+// takes effect from the next notification.
+type Observer interface {
+	ObserveError(ctx context.Context, event Event, e *Error)
+}
+
+// ObserverFunc adapts a function to the Observer interface.
+type ObserverFunc func(ctx context.Context, event Event, e *Error)
+
+// ObserveError calls f(ctx, event, e).
+func (f ObserverFunc) ObserveError(ctx context.Context, event Event, e *Error) {
+	f(ctx, event, e)
+}
+
+type observerEntry struct {
+	id       uint64
+	observer Observer
+}
+
+var (
+	observersMu    sync.Mutex
+	observerNextID uint64
+	observers      atomic.Pointer[[]observerEntry]
+)
+
+// AddObserver registers o and returns a function that removes it.
+func AddObserver(o Observer) (remove func()) {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+
+	observerNextID++
+	id := observerNextID
+	var entries []observerEntry
+	if current := observers.Load(); current != nil {
+		entries = slices.Clone(*current)
+	}
+	entries = append(entries, observerEntry{id: id, observer: o})
+	observers.Store(&entries)
+
+	return func() {
+		observersMu.Lock()
+		defer observersMu.Unlock()
+
+		current := observers.Load()
+		if current == nil {
+			return
+		}
+		entries := slices.DeleteFunc(slices.Clone(*current), func(oe observerEntry) bool {
+			return oe.id == id
+		})
+		if len(entries) == 0 {
+			observers.Store(nil)
+			return
+		}
+		observers.Store(&entries)
+	}
+}
+
+// OnRecord registers fn to be called with every recorded error. It is a
+// shorthand for an Observer that only handles EventRecorded and, like
+// AddObserver, returns a function that removes it.
+func OnRecord(fn func(*Error)) (remove func()) {
+	return AddObserver(ObserverFunc(func(_ context.Context, event Event, e *Error) {
+		if event == EventRecorded {
+			fn(e)
+		}
+	}))
+}
+
+// notify sends event for e to every observer.
+func notify(ctx context.Context, event Event, e *Error) {
+	entries := observers.Load()
+	if entries == nil {
+		return
+	}
+	for _, oe := range *entries {
+		oe.observer.ObserveError(ctx, event, e)
+	}
+}
+
+// Record is RecordContext with context.Background().
+func Record(err error) {
+	RecordContext(context.Background(), err)
+}
+
+// RecordContext reports the first *Error in err's chain to every Observer
+// as EventRecorded, e.g. to count it. Errors without an *Error are ignored.
+func RecordContext(ctx context.Context, err error) {
+	if observers.Load() == nil {
+		return
+	}
+	var e *Error
+	if !errors.As(err, &e) || e == nil {
+		return
+	}
+	notify(ctx, EventRecorded, e)
+}
+
+// ProblemContentType is the media type of RFC 9457 problem details.
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBaseURL is prefixed to the error code to build the problem type
+// URI. When empty, problems use "about:blank".
+var ProblemTypeBaseURL = ""
+
+// Problem is the RFC 9457 problem details representation of an Error.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code"`
+	Domain   string         `json:"domain"`
+	Fields   map[string]any `json:"fields,omitempty"`
+}
+
+// Problem returns the problem details for e. Like Redact, it leaves out the
+// fields of sensitive errors.
+func (e *Error) Problem() Problem {
+	typ := "about:blank"
+	if ProblemTypeBaseURL != "" {
+		typ = ProblemTypeBaseURL + e.Code
+	}
+	fields := e.Fields()
+	if e.sensitive {
+		fields = nil
+	}
+	return Problem{
+		Type:   typ,
+		Title:  e.PublicMessage(),
+		Status: e.HTTPStatus(),
+		Code:   e.Code,
+		Domain: e.Domain,
+		Fields: fields,
+	}
+}
+
+// FromProblem decodes problem details and returns the error variable for its
+// code, copied with the decoded fields when there are any. Unknown codes are
+// rebuilt from the problem members so they are not lost.
+func FromProblem(data []byte) (*Error, error) {
+	var p Problem
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("errz: decode problem: %w", err)
+	}
+	if p.Code == "" {
+		return nil, errors.New("errz: problem has no code")
+	}
+	e, ok := Lookup(p.Code)
+	if !ok {
+		e = &Error{
+			Domain:     p.Domain,
+			Code:       p.Code,
+			Msg:        p.Title,
+			httpStatus: p.Status,
+		}
+	}
+	if len(p.Fields) > 0 {
+		c := *e
+		c.fields = p.Fields
+		e = &c
+	}
+	return e, nil
+}
+
@@ -0,0 +1,649 @@
+package errz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError_IsMatchesByCode(t *testing.T) {
+	copied := *PM0001
+
+	assert.True(t, errors.Is(&copied, PM0001))
+	assert.True(t, errors.Is(fmt.Errorf("charge: %w", &copied), PM0001))
+	assert.False(t, errors.Is(&copied, PM0002))
+	assert.False(t, errors.Is(errors.New("plain"), PM0001))
+}
+
+func TestCodeOf(t *testing.T) {
+	code, ok := CodeOf(fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", AU0001)))
+	assert.True(t, ok)
+	assert.Equal(t, "AU0001", code)
+
+	code, ok = CodeOf(errors.New("plain"))
+	assert.False(t, ok)
+	assert.Empty(t, code)
+
+	_, ok = CodeOf(nil)
+	assert.False(t, ok)
+}
+
+func TestError_WrapDoesNotMutateGlobal(t *testing.T) {
+	cause := errors.New("dial tcp: i/o timeout")
+	wrapped := PM0002.Wrap(cause)
+
+	assert.NotSame(t, PM0002, wrapped)
+	assert.Nil(t, PM0002.Unwrap())
+	assert.Equal(t, cause, wrapped.Unwrap())
+	assert.True(t, errors.Is(wrapped, PM0002))
+	assert.True(t, errors.Is(wrapped, cause))
+	assert.Contains(t, wrapped.Error(), "dial tcp: i/o timeout")
+	assert.NotContains(t, PM0002.Error(), "dial tcp")
+}
+
+func TestError_WithCause(t *testing.T) {
+	cause := errors.New("boom")
+	wrapped := CM0500.WithCause(cause)
+
+	assert.ErrorIs(t, wrapped, cause)
+	assert.ErrorIs(t, wrapped, CM0500)
+}
+
+func TestError_WithParams(t *testing.T) {
+	base := &Error{Domain: "payment", Code: "PM0001", Msg: "need {required}, have {available}", Cause: "short by {required}"}
+
+	e := base.withParams(context.Background(), "required", 10.5, "available", 3)
+
+	assert.Equal(t, "need 10.5, have 3", e.Msg)
+	assert.Equal(t, "short by 10.5", e.Cause)
+	assert.Equal(t, "need {required}, have {available}", base.Msg)
+	assert.ErrorIs(t, e, base)
+}
+
+func TestError_HTTPStatus(t *testing.T) {
+	assert.Equal(t, 402, PM0001.HTTPStatus())
+	assert.Equal(t, 402, PM0001.Wrap(errors.New("x")).HTTPStatus())
+	assert.Equal(t, 500, (&Error{Code: "XX0001"}).HTTPStatus())
+}
+
+func TestError_Problem(t *testing.T) {
+	p := PM0002.Problem()
+	assert.Equal(t, Problem{
+		Type:   "about:blank",
+		Title:  "payment gateway timeout",
+		Status: 504,
+		Code:   "PM0002",
+		Domain: "payment",
+	}, p)
+
+	old := ProblemTypeBaseURL
+	ProblemTypeBaseURL = "https://docs.example.com/errors/"
+	defer func() { ProblemTypeBaseURL = old }()
+
+	assert.Equal(t, "https://docs.example.com/errors/PM0002", PM0002.Problem().Type)
+}
+
+func TestFromProblem(t *testing.T) {
+	e, err := FromProblem([]byte(`{"type":"about:blank","title":"insufficient balance","status":402,"code":"PM0001","domain":"payment"}`))
+	assert.NoError(t, err)
+	assert.Same(t, PM0001, e)
+
+	e, err = FromProblem([]byte(`{"title":"out of stock","status":409,"code":"OD0001","domain":"order"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "OD0001", e.Code)
+	assert.Equal(t, "out of stock", e.Msg)
+	assert.Equal(t, 409, e.HTTPStatus())
+
+	_, err = FromProblem([]byte(`{"title":"no code"}`))
+	assert.EqualError(t, err, "errz: problem has no code")
+
+	_, err = FromProblem([]byte(`not json`))
+	assert.ErrorContains(t, err, "errz: decode problem")
+}
+
+func TestError_Localize(t *testing.T) {
+	e := &Error{
+		Code:         "PM0001",
+		Msg:          "need {required}",
+		translations: map[string]string{"th": "ต้องการ {required}", "zh-hant": "需要 {required}"},
+	}
+
+	assert.Equal(t, "need {required}", e.Localize())
+	assert.Equal(t, "ต้องการ {required}", e.Localize("th-TH"))
+	assert.Equal(t, "ต้องการ {required}", e.Localize("TH_th"))
+	assert.Equal(t, "需要 {required}", e.Localize("zh-Hant-TW"))
+	assert.Equal(t, "ต้องการ {required}", e.Localize("fr", "th"))
+	assert.Equal(t, "need {required}", e.Localize("fr"))
+
+	rendered := e.withParams(context.Background(), "required", 5)
+	assert.Equal(t, "ต้องการ 5", rendered.Localize("th"))
+	assert.Equal(t, "ต้องการ {required}", e.Localize("th"))
+}
+
+func TestError_PublicMessageAndInternalDetail(t *testing.T) {
+	wrapped := PM0002.Wrap(errors.New("dial tcp 10.0.0.7:443: i/o timeout"))
+
+	assert.Equal(t, "payment gateway timeout", wrapped.PublicMessage())
+	assert.Equal(t, "no response from payment gateway: dial tcp 10.0.0.7:443: i/o timeout", wrapped.InternalDetail())
+	assert.Equal(t, "no response from payment gateway", PM0002.InternalDetail())
+}
+
+func TestRedact(t *testing.T) {
+	redacted := Redact(fmt.Errorf("charge: %w", PM0002.Wrap(errors.New("secret host"))))
+
+	assert.Equal(t, "PM0002", redacted.Code)
+	assert.Equal(t, "payment gateway timeout", redacted.Msg)
+	assert.Empty(t, redacted.Cause)
+	assert.Nil(t, redacted.Unwrap())
+	assert.NotContains(t, redacted.Error(), "secret host")
+	assert.Equal(t, "no response from payment gateway", PM0002.Cause)
+
+	assert.Nil(t, Redact(errors.New("plain")))
+}
+
+func TestRedact_Sensitive(t *testing.T) {
+	e := &Error{
+		Code:         "DB0001",
+		Msg:          "replica db-7 lagging",
+		Cause:        "replication slot full",
+		sensitive:    true,
+		translations: map[string]string{"th": "ฐานข้อมูล db-7 ล่าช้า"},
+	}
+
+	redacted := Redact(e)
+
+	assert.Equal(t, RedactedMessage, redacted.Msg)
+	assert.Equal(t, RedactedMessage, redacted.PublicMessage())
+	assert.Equal(t, RedactedMessage, redacted.Localize("th"))
+	assert.Equal(t, RedactedMessage, e.Problem().Title)
+	assert.Equal(t, RedactedMessage, e.Localize("th"))
+	assert.Equal(t, RedactedMessage, e.Localize())
+	assert.ErrorIs(t, redacted, e)
+}
+
+func TestError_LogValue(t *testing.T) {
+	v := PM0002.Wrap(errors.New("i/o timeout")).LogValue()
+
+	got := map[string]string{}
+	for _, a := range v.Group() {
+		got[a.Key] = a.Value.String()
+	}
+
+	assert.Equal(t, map[string]string{
+		"code":     "PM0002",
+		"domain":   "payment",
+		"msg":      "payment gateway timeout",
+		"cause":    "no response from payment gateway",
+		"severity": "error",
+		"category": "dependency",
+		"error":    "i/o timeout",
+	}, got)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(PM0002))
+	assert.True(t, IsRetryable(fmt.Errorf("charge: %w", PM0002.Wrap(errors.New("timeout")))))
+	assert.False(t, IsRetryable(PM0001))
+	assert.False(t, IsRetryable(errors.New("plain")))
+	assert.Equal(t, 3, PM0002.RetryPolicy().MaxAttempts)
+}
+
+func TestRetry_StopsAtPerCodeMaxAttempts(t *testing.T) {
+	transient := &Error{
+		Code:        "XX0001",
+		retryable:   true,
+		retryPolicy: RetryPolicy{MaxAttempts: 4, InitialBackoff: time.Millisecond},
+	}
+
+	calls := 0
+	err := Retry(context.Background(), func(context.Context) error {
+		calls++
+		return transient.Wrap(errors.New("timeout"))
+	}, RetryOptions{MaxAttempts: 10})
+
+	assert.ErrorIs(t, err, transient)
+	assert.Equal(t, 4, calls)
+}
+
+func TestRetry_SucceedsAfterTransientFailure(t *testing.T) {
+	transient := &Error{Code: "XX0001", retryable: true}
+
+	calls := 0
+	err := Retry(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 2 {
+			return transient
+		}
+		return nil
+	}, RetryOptions{InitialBackoff: time.Millisecond})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetry_DoesNotRetryPermanentErrors(t *testing.T) {
+	calls := 0
+	err := Retry(context.Background(), func(context.Context) error {
+		calls++
+		return PM0001
+	}, RetryOptions{})
+
+	assert.ErrorIs(t, err, PM0001)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetry_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	transient := &Error{Code: "XX0001", retryable: true, retryPolicy: RetryPolicy{InitialBackoff: time.Hour}}
+
+	err := Retry(ctx, func(context.Context) error {
+		cancel()
+		return transient
+	}, RetryOptions{})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, transient)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 350 * time.Millisecond}
+
+	assert.Equal(t, 100*time.Millisecond, p.backoff(1))
+	assert.Equal(t, 200*time.Millisecond, p.backoff(2))
+	assert.Equal(t, 350*time.Millisecond, p.backoff(3))
+	assert.Equal(t, 350*time.Millisecond, p.backoff(10))
+}
+
+func TestLookup(t *testing.T) {
+	e, ok := Lookup("PM0001")
+	assert.True(t, ok)
+	assert.Same(t, PM0001, e)
+
+	_, ok = Lookup("ZZ9999")
+	assert.False(t, ok)
+}
+
+func TestAll_InCodeOrder(t *testing.T) {
+	var codes []string
+	for e := range All() {
+		codes = append(codes, e.Code)
+	}
+
+	assert.Equal(t, []string{"AU0001", "CM0000", "CM0400", "CM0500", "PM0001", "PM0002"}, codes)
+}
+
+func TestDomains(t *testing.T) {
+	domains := Domains()
+	assert.Equal(t, []string{"auth", "common", "payment"}, domains)
+
+	domains[0] = "mutated"
+	assert.Equal(t, "auth", Domains()[0])
+}
+
+func TestByDomain(t *testing.T) {
+	var codes []string
+	for e := range ByDomain("payment") {
+		codes = append(codes, e.Code)
+	}
+	assert.Equal(t, []string{"PM0001", "PM0002"}, codes)
+
+	for e := range ByDomain("common") {
+		assert.Equal(t, "CM0000", e.Code)
+		break
+	}
+
+	for range ByDomain("unknown") {
+		t.Fatal("unexpected error for unknown domain")
+	}
+}
+
+func TestError_WithIsCopyOnWrite(t *testing.T) {
+	first := PM0001.With("order_id", "OD-42")
+	second := first.With("sku", "SKU-7")
+
+	assert.Nil(t, PM0001.Fields())
+	assert.Equal(t, map[string]any{"order_id": "OD-42"}, first.Fields())
+	assert.Equal(t, map[string]any{"order_id": "OD-42", "sku": "SKU-7"}, second.Fields())
+	assert.ErrorIs(t, second, PM0001)
+
+	fields := second.Fields()
+	fields["order_id"] = "mutated"
+	assert.Equal(t, "OD-42", second.Fields()["order_id"])
+}
+
+func TestError_FieldsCarriedThroughWrap(t *testing.T) {
+	e := PM0002.With("order_id", "OD-42").Wrap(errors.New("timeout"))
+
+	assert.Equal(t, map[string]any{"order_id": "OD-42"}, e.Fields())
+	assert.Equal(t, map[string]any{"order_id": "OD-42"}, Redact(e).Fields())
+	assert.Equal(t, map[string]any{"order_id": "OD-42"}, e.Problem().Fields)
+}
+
+func TestError_WithParamsAttachesFields(t *testing.T) {
+	e := (&Error{Code: "PM0001", Msg: "need {required}"}).withParams(context.Background(), "required", 10)
+
+	assert.Equal(t, map[string]any{"required": 10}, e.Fields())
+}
+
+func TestError_WithParamsKeepsCauseOnlyParamsOutOfFields(t *testing.T) {
+	base := &Error{
+		Code:         "CM0503",
+		Msg:          "unavailable: {service}",
+		Cause:        "cannot reach {host} for {service}",
+		translations: map[string]string{"th": "{region} ไม่พร้อมใช้งาน"},
+	}
+
+	e := base.withParams(context.Background(), "service", "ledger", "host", "10.0.3.17:5432", "region", "th-1")
+
+	assert.Equal(t, "cannot reach 10.0.3.17:5432 for ledger", e.Cause)
+	assert.Equal(t, map[string]any{"service": "ledger", "region": "th-1"}, e.Fields())
+	assert.NotContains(t, Redact(e).Problem().Fields, "host")
+}
+
+func TestError_LogValueIncludesFields(t *testing.T) {
+	v := PM0001.With("sku", "SKU-7").With("order_id", "OD-42").LogValue()
+
+	var fields []string
+	for _, a := range v.Group() {
+		if a.Key != "fields" {
+			continue
+		}
+		for _, f := range a.Value.Group() {
+			fields = append(fields, f.Key+"="+f.Value.String())
+		}
+	}
+	assert.Equal(t, []string{"order_id=OD-42", "sku=SKU-7"}, fields)
+}
+
+func TestRedact_SensitiveDropsFields(t *testing.T) {
+	e := (&Error{Code: "DB0001", sensitive: true}).With("host", "db-7")
+
+	assert.Nil(t, Redact(e).Fields())
+	assert.Nil(t, e.Problem().Fields)
+}
+
+func TestFromProblem_Fields(t *testing.T) {
+	e, err := FromProblem([]byte(`{"code":"PM0001","fields":{"order_id":"OD-42"}}`))
+	assert.NoError(t, err)
+	assert.NotSame(t, PM0001, e)
+	assert.ErrorIs(t, e, PM0001)
+	assert.Equal(t, map[string]any{"order_id": "OD-42"}, e.Fields())
+	assert.Nil(t, PM0001.Fields())
+}
+
+func TestError_MarshalJSON(t *testing.T) {
+	e := PM0002.With("order_id", "OD-42").Wrap(AU0001.Wrap(errors.New("token revoked")))
+
+	data, err := json.Marshal(e)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"code": "PM0002",
+		"domain": "payment",
+		"msg": "payment gateway timeout",
+		"fields": {"order_id": "OD-42"},
+		"wrapped": {
+			"code": "AU0001",
+			"domain": "auth",
+			"msg": "invalid credentials",
+			"wrapped": {"msg": "token revoked"}
+		}
+	}`, string(data))
+}
+
+func TestError_UnmarshalJSON_KnownCode(t *testing.T) {
+	var e Error
+	err := json.Unmarshal([]byte(`{"code":"PM0002","domain":"payment","msg":"payment gateway timeout","fields":{"order_id":"OD-42"},"wrapped":{"code":"AU0001","wrapped":{"msg":"token revoked"}}}`), &e)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, &e, PM0002)
+	assert.ErrorIs(t, &e, AU0001)
+	assert.Equal(t, PM0002.Cause, e.Cause)
+	assert.Equal(t, 504, e.HTTPStatus())
+	assert.True(t, e.Retryable())
+	assert.Equal(t, map[string]any{"order_id": "OD-42"}, e.Fields())
+	assert.Contains(t, e.Error(), "token revoked")
+	assert.Nil(t, PM0002.Fields())
+}
+
+func TestError_UnmarshalJSON_UnknownCode(t *testing.T) {
+	var e Error
+	err := json.Unmarshal([]byte(`{"code":"OD0009","domain":"order","msg":"reserved stock expired"}`), &e)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "OD0009", e.Code)
+	assert.Equal(t, "order", e.Domain)
+	assert.Equal(t, "reserved stock expired", e.Msg)
+
+	code, ok := CodeOf(&e)
+	assert.True(t, ok)
+	assert.Equal(t, "OD0009", code)
+}
+
+func TestError_UnmarshalJSON_MissingCode(t *testing.T) {
+	var e Error
+	assert.EqualError(t, json.Unmarshal([]byte(`{"msg":"x"}`), &e), "errz: decode error: missing code")
+	assert.Error(t, json.Unmarshal([]byte(`[]`), &e))
+}
+
+func TestError_JSONRoundTrip(t *testing.T) {
+	data, err := json.Marshal(CM0400.With("field", "email"))
+	assert.NoError(t, err)
+
+	var e Error
+	assert.NoError(t, json.Unmarshal(data, &e))
+	assert.ErrorIs(t, &e, CM0400)
+	assert.Equal(t, "email", e.Fields()["field"])
+}
+
+func TestStackTrace_DisabledByDefault(t *testing.T) {
+	e := PM0002.Wrap(errors.New("timeout"))
+
+	assert.Nil(t, e.StackTrace())
+	assert.Equal(t, e.Error(), fmt.Sprintf("%+v", e))
+}
+
+func TestStackTrace_CapturedByWrap(t *testing.T) {
+	EnableStackTraces(true)
+	defer EnableStackTraces(false)
+
+	e := PM0002.Wrap(errors.New("timeout"))
+	frames := e.StackTrace()
+
+	assert.NotEmpty(t, frames)
+	assert.LessOrEqual(t, len(frames), maxStackDepth)
+	assert.True(t, strings.HasSuffix(frames[0].Function, "TestStackTrace_CapturedByWrap"), frames[0].Function)
+	assert.Nil(t, PM0002.StackTrace())
+	assert.Nil(t, Redact(e).StackTrace())
+
+	verbose := fmt.Sprintf("%+v", e)
+	assert.True(t, strings.HasPrefix(verbose, e.Error()+"\n"))
+	assert.Contains(t, verbose, "TestStackTrace_CapturedByWrap")
+	assert.Contains(t, verbose, "errz_gen_test.go:")
+}
+
+// newXX0001 mimics a generated New<CODE> constructor.
+func newXX0001(n int) *Error {
+	return (&Error{Code: "XX0001", Msg: "n={n}"}).withParams(context.Background(), "n", n)
+}
+
+// newXX0001Context mimics a generated New<CODE>Context constructor.
+func newXX0001Context(ctx context.Context, n int) *Error {
+	return (&Error{Code: "XX0001", Msg: "n={n}"}).withParams(ctx, "n", n)
+}
+
+func TestStackTrace_CapturedByConstructor(t *testing.T) {
+	EnableStackTraces(true)
+	defer EnableStackTraces(false)
+
+	frames := newXX0001(1).StackTrace()
+
+	assert.NotEmpty(t, frames)
+	assert.True(t, strings.HasSuffix(frames[0].Function, "TestStackTrace_CapturedByConstructor"), frames[0].Function)
+}
+
+func TestError_Format(t *testing.T) {
+	assert.Equal(t, PM0001.Error(), fmt.Sprintf("%v", PM0001))
+	assert.Equal(t, PM0001.Error(), fmt.Sprintf("%s", PM0001))
+	assert.Equal(t, fmt.Sprintf("%q", PM0001.Error()), fmt.Sprintf("%q", PM0001))
+}
+
+func TestSeverityAndCategory(t *testing.T) {
+	wrapped := fmt.Errorf("charge: %w", PM0002.Wrap(errors.New("timeout")))
+
+	assert.Equal(t, SeverityError, SeverityOf(wrapped))
+	assert.Equal(t, CategoryDependency, CategoryOf(wrapped))
+	assert.True(t, IsDependencyError(wrapped))
+	assert.False(t, IsClientError(wrapped))
+
+	assert.True(t, IsClientError(CM0400))
+	assert.True(t, IsBusinessError(PM0001))
+	assert.True(t, IsServerError(CM0500))
+	assert.Equal(t, SeverityWarning, SeverityOf(AU0001))
+
+	plain := errors.New("plain")
+	assert.Equal(t, SeverityError, SeverityOf(plain))
+	assert.True(t, IsServerError(plain))
+
+	undeclared := &Error{Code: "XX0001"}
+	assert.Equal(t, SeverityError, undeclared.Severity())
+	assert.Equal(t, CategoryServer, undeclared.Category())
+}
+
+func TestSeverity_Level(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, SeverityDebug.Level())
+	assert.Equal(t, slog.LevelInfo, SeverityInfo.Level())
+	assert.Equal(t, slog.LevelWarn, SeverityWarning.Level())
+	assert.Equal(t, slog.LevelError, SeverityError.Level())
+	assert.Greater(t, SeverityCritical.Level(), slog.LevelError)
+}
+
+func TestError_HTTPStatusFromCategory(t *testing.T) {
+	assert.Equal(t, 400, (&Error{category: CategoryClient}).HTTPStatus())
+	assert.Equal(t, 422, (&Error{category: CategoryBusiness}).HTTPStatus())
+	assert.Equal(t, 502, (&Error{category: CategoryDependency}).HTTPStatus())
+	assert.Equal(t, 500, (&Error{category: CategoryServer}).HTTPStatus())
+	assert.Equal(t, 404, (&Error{category: CategoryClient, httpStatus: 404}).HTTPStatus())
+}
+
+func TestRecord_CallsHooksInOrder(t *testing.T) {
+	defer observers.Store(nil)
+
+	var got []string
+	OnRecord(func(e *Error) { got = append(got, "first:"+e.Code) })
+	OnRecord(func(e *Error) { got = append(got, "second:"+e.Code) })
+
+	Record(fmt.Errorf("charge: %w", PM0002.Wrap(errors.New("timeout"))))
+	Record(errors.New("plain"))
+	Record(nil)
+
+	assert.Equal(t, []string{"first:PM0002", "second:PM0002"}, got)
+}
+
+func TestOnRecord_Remove(t *testing.T) {
+	defer observers.Store(nil)
+
+	var got []string
+	removeFirst := OnRecord(func(e *Error) { got = append(got, "first:"+e.Code) })
+	OnRecord(func(e *Error) { got = append(got, "second:"+e.Code) })
+
+	removeFirst()
+	removeFirst()
+	Record(PM0002)
+
+	assert.Equal(t, []string{"second:PM0002"}, got)
+}
+
+type ctxKey struct{}
+
+func TestAddObserver_CreatedAndRecorded(t *testing.T) {
+	type call struct {
+		event Event
+		code  string
+		span  any
+	}
+
+	var calls []call
+	remove := AddObserver(ObserverFunc(func(ctx context.Context, event Event, e *Error) {
+		calls = append(calls, call{event: event, code: e.Code, span: ctx.Value(ctxKey{})})
+	}))
+
+	wrapped := PM0002.Wrap(errors.New("timeout"))
+	CM0400.WithCause(errors.New("bad json"))
+	newXX0001(1)
+	RecordContext(context.WithValue(context.Background(), ctxKey{}, "span-1"), fmt.Errorf("charge: %w", wrapped))
+	PM0001.With("order_id", "OD-42")
+
+	remove()
+	PM0002.Wrap(errors.New("after remove"))
+	Record(PM0001)
+
+	assert.Equal(t, []call{
+		{event: EventCreated, code: "PM0002"},
+		{event: EventCreated, code: "CM0400"},
+		{event: EventCreated, code: "XX0001"},
+		{event: EventRecorded, code: "PM0002", span: "span-1"},
+	}, calls)
+	assert.Nil(t, observers.Load())
+}
+
+func TestAddObserver_CreatedWithContext(t *testing.T) {
+	var spans []any
+	remove := AddObserver(ObserverFunc(func(ctx context.Context, event Event, e *Error) {
+		spans = append(spans, ctx.Value(ctxKey{}))
+	}))
+	defer remove()
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "span-1")
+	e := PM0002.WrapContext(ctx, errors.New("timeout"))
+	rendered := newXX0001Context(ctx, 7)
+
+	assert.Equal(t, []any{"span-1", "span-1"}, spans)
+	assert.ErrorIs(t, e, PM0002)
+	assert.Equal(t, "n=7", rendered.Msg)
+}
+
+func TestAddObserver_Ordering(t *testing.T) {
+	var order []string
+	removeA := AddObserver(ObserverFunc(func(context.Context, Event, *Error) { order = append(order, "a") }))
+	removeB := AddObserver(ObserverFunc(func(context.Context, Event, *Error) { order = append(order, "b") }))
+	removeC := AddObserver(ObserverFunc(func(context.Context, Event, *Error) { order = append(order, "c") }))
+	defer removeA()
+	defer removeC()
+
+	Record(PM0001)
+	removeB()
+	removeB() // removing twice is a no-op
+	Record(PM0001)
+
+	assert.Equal(t, []string{"a", "b", "c", "a", "c"}, order)
+}
+
+func TestAddObserver_Concurrent(t *testing.T) {
+	var count atomic.Int64
+	remove := AddObserver(ObserverFunc(func(context.Context, Event, *Error) { count.Add(1) }))
+	defer remove()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Record(PM0001)
+				AddObserver(ObserverFunc(func(context.Context, Event, *Error) {}))()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(2000), count.Load())
+}
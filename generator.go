@@ -4,14 +4,17 @@ package errz
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 	"unicode"
 
-	"golang.org/x/sync/errgroup"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 type Generator struct {
@@ -19,32 +22,86 @@ type Generator struct {
 	DefinitionsDir string
 	OutputPath     string
 	OutputDocDir   string
+
+	// RequiredLocales lists the BCP 47 tags every definition must translate.
+	RequiredLocales []string
+
+	// DomainsPath is the domain registry mapping code prefixes to domains.
+	// When empty, prefixes are not checked and markdown titles are derived
+	// from the domain name.
+	DomainsPath string
+	// DomainsSchemaPath is the JSON Schema the domain registry is validated
+	// against, if set.
+	DomainsSchemaPath string
+
+	// Concurrency bounds how many definition files are read and validated
+	// at once. Zero means runtime.GOMAXPROCS(0).
+	Concurrency int
 }
 
+// Run validates and loads the definitions and writes the Go file and the
+// markdown. Every schema, parse and semantic failure across all files is
+// collected before returning, as Diagnostics.
 func (g *Generator) Run() error {
-	var errors map[string]Error
+	errors, retired, domains, ds := g.load()
+	if ds.HasErrors() {
+		return ds
+	}
+
+	// Generate code content
+	return generate(g.OutputPath, g.OutputDocDir, errors, retired, domains)
+}
+
+// Validate validates and loads the definitions without writing anything,
+// and returns every problem found. Tools such as editor plugins and CI bots
+// can inspect the result instead of parsing the error text of Run.
+func (g *Generator) Validate() Diagnostics {
+	_, _, _, ds := g.load()
+	return ds
+}
+
+// load validates and loads the definitions and the domain registry, and
+// collects every failure as Diagnostics.
+func (g *Generator) load() (map[string]definition, map[string]tombstone, map[string]domainEntry, Diagnostics) {
+	var (
+		domains    map[string]domainEntry
+		domainsErr error
+	)
 
-	var eg errgroup.Group
+	var wg sync.WaitGroup
+	if g.DomainsPath != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var schema *gojsonschema.Schema
+			if g.DomainsSchemaPath != "" {
+				if schema, domainsErr = compileSchema(g.DomainsSchemaPath); domainsErr != nil {
+					return
+				}
+			}
+			domains, domainsErr = loadDomains(g.DomainsPath, schema)
+		}()
+	}
 
-	eg.Go(func() error {
-		return validateAllJSONFiles(g.SchemaPath, g.DefinitionsDir)
-	})
+	// The schema is compiled once for all files. When it cannot be, the
+	// definitions are still loaded so that their failures are reported too.
+	schema, schemaErr := compileSchema(g.SchemaPath)
+	errors, retired, loadErr := loadAndValidateDefinitions(g.DefinitionsDir, schema, g.Concurrency)
 
-	eg.Go(func() error {
-		var err error
-		errors, err = loadErrorDefinitions(g.DefinitionsDir)
-		return err
-	})
+	wg.Wait()
 
-	if err := eg.Wait(); err != nil {
-		return err
+	// Semantic checks run on whatever could be loaded, so that they are
+	// reported together with the schema and parse failures.
+	var registryErr error
+	if domains != nil {
+		registryErr = validateDomains(errors, retired, domains)
 	}
+	ds := newDiagnostics(schemaErr, loadErr, domainsErr, validateDefinitions(errors, g.RequiredLocales), registryErr)
 
-	// Generate code content
-	return generate(g.OutputPath, g.OutputDocDir, errors)
+	return errors, retired, domains, ds
 }
 
-func generate(outputPath, outputDirPath string, errors map[string]Error) error {
+func generate(outputPath, outputDirPath string, errors map[string]definition, retired map[string]tombstone, domains map[string]domainEntry) error {
 	path := strings.ToLower(outputPath)
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 		return fmt.Errorf("failed to create output dir: %w", err)
@@ -56,7 +113,7 @@ func generate(outputPath, outputDirPath string, errors map[string]Error) error {
 		return fmt.Errorf("failed to write go content: %w", err)
 	}
 
-	domainGroups := make(map[string]map[string]Error)
+	domainGroups := make(map[string]map[string]definition)
 	for code, def := range errors {
 		domain := def.Domain
 		if domain == "" {
@@ -64,14 +121,31 @@ func generate(outputPath, outputDirPath string, errors map[string]Error) error {
 		}
 
 		if _, ok := domainGroups[domain]; !ok {
-			domainGroups[domain] = make(map[string]Error)
+			domainGroups[domain] = make(map[string]definition)
+		}
+
+		if def.ReplacedBy != "" {
+			def.replacedByDomain = errors[def.ReplacedBy].Domain
 		}
 
 		domainGroups[domain][code] = def
 	}
 
+	retiredGroups := make(map[string][]tombstone)
+	for _, t := range retired {
+		if _, ok := domainGroups[t.Domain]; !ok {
+			domainGroups[t.Domain] = make(map[string]definition)
+		}
+		retiredGroups[t.Domain] = append(retiredGroups[t.Domain], t)
+	}
+
+	registered := make(map[string]domainEntry, len(domains))
+	for _, entry := range domains {
+		registered[entry.Domain] = entry
+	}
+
 	for domain, group := range domainGroups {
-		if err := writeMarkdownFile(outputDirPath, domain, group); err != nil {
+		if err := writeMarkdownFile(outputDirPath, domain, registered[domain], group, retiredGroups[domain]); err != nil {
 			return fmt.Errorf("failed to write markdown for domain %q: %w", domain, err)
 		}
 	}
@@ -82,7 +156,7 @@ func generate(outputPath, outputDirPath string, errors map[string]Error) error {
 var errLenErrors = errors.New("no error definitions provided")
 
 // generateGoContent generates the Go code content from error definitions.
-func generateGoContent(errors map[string]Error) (string, error) {
+func generateGoContent(errors map[string]definition) (string, error) {
 	if len(errors) == 0 {
 		return "", errLenErrors
 	}
@@ -100,7 +174,23 @@ func generateGoContent(errors map[string]Error) (string, error) {
 	builder.WriteString("// Code generated by gen_errors/gen.go; DO NOT EDIT.\n")
 	builder.WriteString("package errz\n\n")
 
-	builder.WriteString("import \"fmt\"\n\n")
+	builder.WriteString("import (\n")
+	builder.WriteString("\t\"cmp\"\n")
+	builder.WriteString("\t\"context\"\n")
+	builder.WriteString("\t\"encoding/json\"\n")
+	builder.WriteString("\t\"errors\"\n")
+	builder.WriteString("\t\"fmt\"\n")
+	builder.WriteString("\t\"io\"\n")
+	builder.WriteString("\t\"iter\"\n")
+	builder.WriteString("\t\"log/slog\"\n")
+	builder.WriteString("\t\"maps\"\n")
+	builder.WriteString("\t\"runtime\"\n")
+	builder.WriteString("\t\"slices\"\n")
+	builder.WriteString("\t\"strings\"\n")
+	builder.WriteString("\t\"sync\"\n")
+	builder.WriteString("\t\"sync/atomic\"\n")
+	builder.WriteString("\t\"time\"\n")
+	builder.WriteString(")\n\n")
 
 	// Error struct definition
 	builder.WriteString("// Error represents a centralized error definition.\n")
@@ -109,30 +199,947 @@ func generateGoContent(errors map[string]Error) (string, error) {
 	builder.WriteString("\tCode        string\n")
 	builder.WriteString("\tMsg         string\n")
 	builder.WriteString("\tCause       string\n")
+	builder.WriteString("\n")
+	builder.WriteString("\thttpStatus   int               // HTTP status declared in the definition\n")
+	builder.WriteString("\tseverity     Severity          // alerting severity declared in the definition\n")
+	builder.WriteString("\tcategory     Category          // fault category declared in the definition\n")
+	builder.WriteString("\tretryable    bool              // the failed operation may be retried\n")
+	builder.WriteString("\tretryPolicy  RetryPolicy       // backoff hints for retryable errors\n")
+	builder.WriteString("\tsensitive    bool              // msg must not reach external clients\n")
+	builder.WriteString("\ttranslations map[string]string // msg keyed by lowercase BCP 47 tag\n")
+	builder.WriteString("\tfields       map[string]any    // context attached by With; never mutated in place\n")
+	builder.WriteString("\terr          error             // underlying error attached by Wrap\n")
+	builder.WriteString("\tstack        []uintptr         // captured by Wrap and New<CODE> when enabled\n")
 	builder.WriteString("}\n\n")
 
 	// Implement error interface
 	builder.WriteString("func (e *Error) Error() string {\n")
-	builder.WriteString("\treturn fmt.Sprintf(\"[%s][%s] msg: %s | cause: %s\",\n")
+	builder.WriteString("\tmsg := fmt.Sprintf(\"[%s][%s] msg: %s | cause: %s\",\n")
 	builder.WriteString("\t\te.Domain, e.Code, e.Msg, e.Cause)\n")
+	builder.WriteString("\tif e.err != nil {\n")
+	builder.WriteString("\t\tmsg += \": \" + e.err.Error()\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn msg\n")
+	builder.WriteString("}\n\n")
+
+	// Wrapping returns a copy so the shared variables are never mutated
+	builder.WriteString("// Wrap returns a copy of e carrying err as its underlying error.\n")
+	builder.WriteString("func (e *Error) Wrap(err error) *Error {\n")
+	builder.WriteString("\treturn e.wrap(context.Background(), err)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// WrapContext is like Wrap but passes ctx to observers, so they can reach\n")
+	builder.WriteString("// the trace span of the operation that failed.\n")
+	builder.WriteString("func (e *Error) WrapContext(ctx context.Context, err error) *Error {\n")
+	builder.WriteString("\treturn e.wrap(ctx, err)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// WithCause is an alias for Wrap.\n")
+	builder.WriteString("func (e *Error) WithCause(err error) *Error {\n")
+	builder.WriteString("\treturn e.wrap(context.Background(), err)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// wrap is called directly by the exported wrapping methods so that the\n")
+	builder.WriteString("// captured stack starts at their caller.\n")
+	builder.WriteString("func (e *Error) wrap(ctx context.Context, err error) *Error {\n")
+	builder.WriteString("\tc := *e\n")
+	builder.WriteString("\tc.err = err\n")
+	builder.WriteString("\tc.stack = callers()\n")
+	builder.WriteString("\tnotify(ctx, EventCreated, &c)\n")
+	builder.WriteString("\treturn &c\n")
+	builder.WriteString("}\n\n")
+
+	// Structured context, copy-on-write like Wrap
+	builder.WriteString("// With returns a copy of e with key set to value in its fields.\n")
+	builder.WriteString("func (e *Error) With(key string, value any) *Error {\n")
+	builder.WriteString("\tc := *e\n")
+	builder.WriteString("\tc.fields = make(map[string]any, len(e.fields)+1)\n")
+	builder.WriteString("\tmaps.Copy(c.fields, e.fields)\n")
+	builder.WriteString("\tc.fields[key] = value\n")
+	builder.WriteString("\treturn &c\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Fields returns a copy of the fields attached with With, or nil.\n")
+	builder.WriteString("func (e *Error) Fields() map[string]any {\n")
+	builder.WriteString("\treturn maps.Clone(e.fields)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Unwrap returns the underlying error attached by Wrap, if any.\n")
+	builder.WriteString("func (e *Error) Unwrap() error {\n")
+	builder.WriteString("\treturn e.err\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// HTTPStatus returns the HTTP status declared for the error. Without one it\n")
+	builder.WriteString("// is derived from the category, defaulting to 500.\n")
+	builder.WriteString("func (e *Error) HTTPStatus() int {\n")
+	builder.WriteString("\tif e.httpStatus != 0 {\n")
+	builder.WriteString("\t\treturn e.httpStatus\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tswitch e.category {\n")
+	builder.WriteString("\tcase CategoryClient:\n")
+	builder.WriteString("\t\treturn 400\n")
+	builder.WriteString("\tcase CategoryBusiness:\n")
+	builder.WriteString("\t\treturn 422\n")
+	builder.WriteString("\tcase CategoryDependency:\n")
+	builder.WriteString("\t\treturn 502\n")
+	builder.WriteString("\tdefault:\n")
+	builder.WriteString("\t\treturn 500\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Localize returns the message translated into the first of tags that has a\n")
+	builder.WriteString("// translation. Each tag falls back to its parent (\"th-TH\" to \"th\") before the\n")
+	builder.WriteString("// next tag is tried, and Msg is returned when nothing matches. Sensitive\n")
+	builder.WriteString("// errors always return RedactedMessage.\n")
+	builder.WriteString("func (e *Error) Localize(tags ...string) string {\n")
+	builder.WriteString("\tif e.sensitive {\n")
+	builder.WriteString("\t\treturn RedactedMessage\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tfor _, tag := range tags {\n")
+	builder.WriteString("\t\ttag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), \"_\", \"-\"))\n")
+	builder.WriteString("\t\tfor tag != \"\" {\n")
+	builder.WriteString("\t\t\tif msg, ok := e.translations[tag]; ok {\n")
+	builder.WriteString("\t\t\t\treturn msg\n")
+	builder.WriteString("\t\t\t}\n")
+	builder.WriteString("\t\t\ti := strings.LastIndexByte(tag, '-')\n")
+	builder.WriteString("\t\t\tif i < 0 {\n")
+	builder.WriteString("\t\t\t\tbreak\n")
+	builder.WriteString("\t\t\t}\n")
+	builder.WriteString("\t\t\ttag = tag[:i]\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.Msg\n")
+	builder.WriteString("}\n\n")
+
+	// Shared helper behind the generated New<CODE> constructors
+	builder.WriteString("// withParams returns a copy of e with {name} placeholders in Msg and Cause\n")
+	builder.WriteString("// replaced by the given name/value pairs. Pairs used by Msg or a translation\n")
+	builder.WriteString("// are also attached as fields; pairs used only by Cause are not, since fields\n")
+	builder.WriteString("// reach clients and the cause is for operators only.\n")
+	builder.WriteString("// Observers are notified with ctx. It is only called by the generated\n")
+	builder.WriteString("// New<CODE> constructors.\n")
+	builder.WriteString("func (e *Error) withParams(ctx context.Context, pairs ...any) *Error {\n")
+	builder.WriteString("\tc := *e\n")
+	builder.WriteString("\tc.stack = callers()\n")
+	builder.WriteString("\tc.fields = make(map[string]any, len(e.fields)+len(pairs)/2)\n")
+	builder.WriteString("\tmaps.Copy(c.fields, e.fields)\n")
+	builder.WriteString("\toldnew := make([]string, 0, len(pairs))\n")
+	builder.WriteString("\tfor i := 0; i+1 < len(pairs); i += 2 {\n")
+	builder.WriteString("\t\tname := fmt.Sprint(pairs[i])\n")
+	builder.WriteString("\t\toldnew = append(oldnew, \"{\"+name+\"}\", fmt.Sprint(pairs[i+1]))\n")
+	builder.WriteString("\t\tif e.isPublicParam(name) {\n")
+	builder.WriteString("\t\t\tc.fields[name] = pairs[i+1]\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tr := strings.NewReplacer(oldnew...)\n")
+	builder.WriteString("\tc.Msg = r.Replace(c.Msg)\n")
+	builder.WriteString("\tc.Cause = r.Replace(c.Cause)\n")
+	builder.WriteString("\tif c.translations != nil {\n")
+	builder.WriteString("\t\ttranslations := make(map[string]string, len(c.translations))\n")
+	builder.WriteString("\t\tfor tag, msg := range c.translations {\n")
+	builder.WriteString("\t\t\ttranslations[tag] = r.Replace(msg)\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t\tc.translations = translations\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tnotify(ctx, EventCreated, &c)\n")
+	builder.WriteString("\treturn &c\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// isPublicParam reports whether the {name} placeholder appears in Msg or in a\n")
+	builder.WriteString("// translation, i.e. in text that may be shown to clients.\n")
+	builder.WriteString("func (e *Error) isPublicParam(name string) bool {\n")
+	builder.WriteString("\tplaceholder := \"{\" + name + \"}\"\n")
+	builder.WriteString("\tif strings.Contains(e.Msg, placeholder) {\n")
+	builder.WriteString("\t\treturn true\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tfor _, msg := range e.translations {\n")
+	builder.WriteString("\t\tif strings.Contains(msg, placeholder) {\n")
+	builder.WriteString("\t\t\treturn true\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn false\n")
+	builder.WriteString("}\n\n")
+
+	// Match by code so copies and decoded errors satisfy errors.Is
+	builder.WriteString("// Is reports whether target is an *Error with the same code.\n")
+	builder.WriteString("func (e *Error) Is(target error) bool {\n")
+	builder.WriteString("\tt, ok := target.(*Error)\n")
+	builder.WriteString("\tif !ok || e == nil || t == nil {\n")
+	builder.WriteString("\t\treturn false\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.Code == t.Code\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// CodeOf returns the code of the first *Error in err's chain.\n")
+	builder.WriteString("func CodeOf(err error) (string, bool) {\n")
+	builder.WriteString("\tvar e *Error\n")
+	builder.WriteString("\tif !errors.As(err, &e) || e == nil {\n")
+	builder.WriteString("\t\treturn \"\", false\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.Code, true\n")
 	builder.WriteString("}\n\n")
 
 	// Individual error variables
 	builder.WriteString("var (\n")
 	for _, code := range codes {
 		errDef := errors[code]
+		if errDef.Deprecated {
+			builder.WriteString(fmt.Sprintf("\t// %s\n", deprecationNotice(code, errDef)))
+		}
 		builder.WriteString(fmt.Sprintf("\t%s = &Error{\n", code))
 		builder.WriteString(fmt.Sprintf("\t\tDomain: \"%s\",\n", escape(errDef.Domain)))
 		builder.WriteString(fmt.Sprintf("\t\tCode: \"%s\",\n", escape(errDef.Code)))
 		builder.WriteString(fmt.Sprintf("\t\tMsg: \"%s\",\n", escape(errDef.Msg)))
 		builder.WriteString(fmt.Sprintf("\t\tCause: \"%s\",\n", escape(errDef.Cause)))
+		if errDef.HTTPStatus != 0 {
+			builder.WriteString(fmt.Sprintf("\t\thttpStatus: %d,\n", errDef.HTTPStatus))
+		}
+		if errDef.Severity != "" {
+			builder.WriteString(fmt.Sprintf("\t\tseverity: %s,\n", severityConsts[errDef.Severity]))
+		}
+		if errDef.Category != "" {
+			builder.WriteString(fmt.Sprintf("\t\tcategory: %s,\n", categoryConsts[errDef.Category]))
+		}
+		if errDef.Retryable {
+			builder.WriteString("\t\tretryable: true,\n")
+		}
+		if r := errDef.Retry; r != nil {
+			builder.WriteString(fmt.Sprintf(
+				"\t\tretryPolicy: RetryPolicy{MaxAttempts: %d, InitialBackoff: %d * time.Millisecond, MaxBackoff: %d * time.Millisecond},\n",
+				r.MaxAttempts, r.BackoffMS, r.MaxBackoffMS,
+			))
+		}
+		if errDef.Sensitive {
+			builder.WriteString("\t\tsensitive: true,\n")
+		}
+		if len(errDef.Translations) > 0 {
+			builder.WriteString("\t\ttranslations: map[string]string{\n")
+			for _, tag := range sortedKeys(errDef.Translations) {
+				builder.WriteString(fmt.Sprintf("\t\t\t\"%s\": \"%s\",\n", strings.ToLower(tag), escape(errDef.Translations[tag])))
+			}
+			builder.WriteString("\t\t},\n")
+		}
 		builder.WriteString("\t}\n")
 	}
 	builder.WriteString(")\n\n")
 
+	writeRegistryContent(&builder, codes, errors)
+
+	writeRedactionContent(&builder)
+	writeLogValueContent(&builder)
+	writeClassificationContent(&builder)
+	writeRetryContent(&builder)
+	writeJSONContent(&builder)
+	writeStackContent(&builder)
+	writeObserverContent(&builder)
+	writeProblemContent(&builder)
+
+	// Typed constructors for parameterized messages
+	for _, code := range codes {
+		errDef := errors[code]
+		if len(errDef.Params) == 0 {
+			continue
+		}
+
+		args := make([]string, 0, len(errDef.Params))
+		for _, p := range errDef.Params {
+			args = append(args, fmt.Sprintf("%s %s", p.Name, p.Type))
+		}
+
+		builder.WriteString(fmt.Sprintf("// New%s returns a copy of %s with its message parameters filled in.\n", code, code))
+		if errDef.Deprecated {
+			builder.WriteString("//\n")
+			builder.WriteString(fmt.Sprintf("// %s\n", deprecationNotice(code, errDef)))
+		}
+		builder.WriteString(fmt.Sprintf("func New%s(%s) *Error {\n", code, strings.Join(args, ", ")))
+		builder.WriteString(fmt.Sprintf("\treturn %s.withParams(context.Background(),\n", code))
+		for _, p := range errDef.Params {
+			builder.WriteString(fmt.Sprintf("\t\t\"%s\", %s,\n", p.Name, p.Name))
+		}
+		builder.WriteString("\t)\n")
+		builder.WriteString("}\n\n")
+
+		builder.WriteString(fmt.Sprintf("// New%sContext is like New%s but passes ctx to observers.\n", code, code))
+		if errDef.Deprecated {
+			builder.WriteString("//\n")
+			builder.WriteString(fmt.Sprintf("// %s\n", deprecationNotice(code, errDef)))
+		}
+		builder.WriteString(fmt.Sprintf("func New%sContext(ctx context.Context, %s) *Error {\n", code, strings.Join(args, ", ")))
+		builder.WriteString(fmt.Sprintf("\treturn %s.withParams(ctx,\n", code))
+		for _, p := range errDef.Params {
+			builder.WriteString(fmt.Sprintf("\t\t\"%s\", %s,\n", p.Name, p.Name))
+		}
+		builder.WriteString("\t)\n")
+		builder.WriteString("}\n\n")
+	}
+
 	return builder.String(), nil
 }
 
+// deprecationNotice returns the "Deprecated:" paragraph for a deprecated
+// definition, recognized by staticcheck and gopls.
+func deprecationNotice(code string, def definition) string {
+	if def.ReplacedBy == "" {
+		return fmt.Sprintf("Deprecated: %s is retired and has no replacement.", code)
+	}
+	return fmt.Sprintf("Deprecated: use errz.%s instead.", def.ReplacedBy)
+}
+
+// writeRegistryContent writes the runtime registry of every generated error,
+// kept in code order, and its lookup functions.
+func writeRegistryContent(builder *strings.Builder, codes []string, errors map[string]definition) {
+	builder.WriteString("// registry lists every error in code order.\n")
+	builder.WriteString("var registry = []*Error{\n")
+	for _, code := range codes {
+		builder.WriteString(fmt.Sprintf("\t%s,\n", code))
+	}
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("var byCode = map[string]*Error{\n")
+	for _, code := range codes {
+		builder.WriteString(fmt.Sprintf("\t\"%s\": %s,\n", code, code))
+	}
+	builder.WriteString("}\n\n")
+
+	seen := make(map[string]bool)
+	var domains []string
+	for _, code := range codes {
+		if domain := errors[code].Domain; !seen[domain] {
+			seen[domain] = true
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+
+	builder.WriteString("// domains lists every domain in alphabetical order.\n")
+	builder.WriteString("var domains = []string{\n")
+	for _, domain := range domains {
+		builder.WriteString(fmt.Sprintf("\t\"%s\",\n", escape(domain)))
+	}
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Lookup returns the error variable defined for code.\n")
+	builder.WriteString("func Lookup(code string) (*Error, bool) {\n")
+	builder.WriteString("\te, ok := byCode[code]\n")
+	builder.WriteString("\treturn e, ok\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// All returns every error variable in code order.\n")
+	builder.WriteString("func All() iter.Seq[*Error] {\n")
+	builder.WriteString("\treturn slices.Values(registry)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Domains returns every domain in alphabetical order.\n")
+	builder.WriteString("func Domains() []string {\n")
+	builder.WriteString("\treturn slices.Clone(domains)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// ByDomain returns the error variables of domain in code order.\n")
+	builder.WriteString("func ByDomain(domain string) iter.Seq[*Error] {\n")
+	builder.WriteString("\treturn func(yield func(*Error) bool) {\n")
+	builder.WriteString("\t\tfor _, e := range registry {\n")
+	builder.WriteString("\t\t\tif e.Domain == domain && !yield(e) {\n")
+	builder.WriteString("\t\t\t\treturn\n")
+	builder.WriteString("\t\t\t}\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+}
+
+// writeRedactionContent writes the accessors and the Redact function that
+// separate user-facing text from operator-facing detail.
+func writeRedactionContent(builder *strings.Builder) {
+	builder.WriteString("// RedactedMessage replaces the message of sensitive errors sent to external\n")
+	builder.WriteString("// clients.\n")
+	builder.WriteString("var RedactedMessage = \"an unexpected error occurred\"\n\n")
+
+	builder.WriteString("// PublicMessage returns the message that may be shown to external clients.\n")
+	builder.WriteString("func (e *Error) PublicMessage() string {\n")
+	builder.WriteString("\tif e.sensitive {\n")
+	builder.WriteString("\t\treturn RedactedMessage\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.Msg\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// InternalDetail returns the operator-facing cause followed by the wrapped\n")
+	builder.WriteString("// error, if any. It must not be sent to external clients.\n")
+	builder.WriteString("func (e *Error) InternalDetail() string {\n")
+	builder.WriteString("\tif e.err == nil {\n")
+	builder.WriteString("\t\treturn e.Cause\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.Cause + \": \" + e.err.Error()\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Redact returns a copy of the first *Error in err's chain that is safe to\n")
+	builder.WriteString("// serialize to external clients: the cause and wrapped error are dropped and\n")
+	builder.WriteString("// sensitive errors have their message replaced by RedactedMessage and their\n")
+	builder.WriteString("// fields removed. It returns nil when err carries no *Error.\n")
+	builder.WriteString("func Redact(err error) *Error {\n")
+	builder.WriteString("\tvar e *Error\n")
+	builder.WriteString("\tif !errors.As(err, &e) || e == nil {\n")
+	builder.WriteString("\t\treturn nil\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tc := *e\n")
+	builder.WriteString("\tc.Msg = e.PublicMessage()\n")
+	builder.WriteString("\tc.Cause = \"\"\n")
+	builder.WriteString("\tc.err = nil\n")
+	builder.WriteString("\tc.stack = nil\n")
+	builder.WriteString("\tif e.sensitive {\n")
+	builder.WriteString("\t\tc.translations = nil\n")
+	builder.WriteString("\t\tc.fields = nil\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn &c\n")
+	builder.WriteString("}\n\n")
+}
+
+// writeLogValueContent writes the slog.LogValuer implementation.
+func writeLogValueContent(builder *strings.Builder) {
+	builder.WriteString("// LogValue implements slog.LogValuer, logging the error as a group of its\n")
+	builder.WriteString("// fields so log queries can filter by code.\n")
+	builder.WriteString("func (e *Error) LogValue() slog.Value {\n")
+	builder.WriteString("\tattrs := []slog.Attr{\n")
+	builder.WriteString("\t\tslog.String(\"code\", e.Code),\n")
+	builder.WriteString("\t\tslog.String(\"domain\", e.Domain),\n")
+	builder.WriteString("\t\tslog.String(\"msg\", e.Msg),\n")
+	builder.WriteString("\t\tslog.String(\"cause\", e.Cause),\n")
+	builder.WriteString("\t\tslog.String(\"severity\", string(e.Severity())),\n")
+	builder.WriteString("\t\tslog.String(\"category\", string(e.Category())),\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif e.err != nil {\n")
+	builder.WriteString("\t\tattrs = append(attrs, slog.String(\"error\", e.err.Error()))\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif len(e.fields) > 0 {\n")
+	builder.WriteString("\t\tfields := make([]any, 0, len(e.fields))\n")
+	builder.WriteString("\t\tfor _, k := range slices.Sorted(maps.Keys(e.fields)) {\n")
+	builder.WriteString("\t\t\tfields = append(fields, slog.Any(k, e.fields[k]))\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t\tattrs = append(attrs, slog.Group(\"fields\", fields...))\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn slog.GroupValue(attrs...)\n")
+	builder.WriteString("}\n\n")
+}
+
+// severityConsts and categoryConsts map the schema enums to the generated
+// constant names.
+var (
+	severityConsts = map[string]string{
+		"debug":    "SeverityDebug",
+		"info":     "SeverityInfo",
+		"warning":  "SeverityWarning",
+		"error":    "SeverityError",
+		"critical": "SeverityCritical",
+	}
+	categoryConsts = map[string]string{
+		"client":     "CategoryClient",
+		"server":     "CategoryServer",
+		"dependency": "CategoryDependency",
+		"business":   "CategoryBusiness",
+	}
+)
+
+// writeClassificationContent writes the Severity and Category types and the
+// helpers that classify arbitrary errors.
+func writeClassificationContent(builder *strings.Builder) {
+	builder.WriteString("// Severity is the alerting severity of an error.\n")
+	builder.WriteString("type Severity string\n\n")
+
+	builder.WriteString("const (\n")
+	builder.WriteString("\tSeverityDebug    Severity = \"debug\"\n")
+	builder.WriteString("\tSeverityInfo     Severity = \"info\"\n")
+	builder.WriteString("\tSeverityWarning  Severity = \"warning\"\n")
+	builder.WriteString("\tSeverityError    Severity = \"error\"\n")
+	builder.WriteString("\tSeverityCritical Severity = \"critical\"\n")
+	builder.WriteString(")\n\n")
+
+	builder.WriteString("// Level returns the slog level matching s. Critical maps above slog.LevelError.\n")
+	builder.WriteString("func (s Severity) Level() slog.Level {\n")
+	builder.WriteString("\tswitch s {\n")
+	builder.WriteString("\tcase SeverityDebug:\n")
+	builder.WriteString("\t\treturn slog.LevelDebug\n")
+	builder.WriteString("\tcase SeverityInfo:\n")
+	builder.WriteString("\t\treturn slog.LevelInfo\n")
+	builder.WriteString("\tcase SeverityWarning:\n")
+	builder.WriteString("\t\treturn slog.LevelWarn\n")
+	builder.WriteString("\tcase SeverityCritical:\n")
+	builder.WriteString("\t\treturn slog.LevelError + 4\n")
+	builder.WriteString("\tdefault:\n")
+	builder.WriteString("\t\treturn slog.LevelError\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Category classifies who is at fault for an error.\n")
+	builder.WriteString("type Category string\n\n")
+
+	builder.WriteString("const (\n")
+	builder.WriteString("\tCategoryClient     Category = \"client\"\n")
+	builder.WriteString("\tCategoryServer     Category = \"server\"\n")
+	builder.WriteString("\tCategoryDependency Category = \"dependency\"\n")
+	builder.WriteString("\tCategoryBusiness   Category = \"business\"\n")
+	builder.WriteString(")\n\n")
+
+	builder.WriteString("// Severity returns the declared severity, or SeverityError when undeclared.\n")
+	builder.WriteString("func (e *Error) Severity() Severity {\n")
+	builder.WriteString("\tif e.severity == \"\" {\n")
+	builder.WriteString("\t\treturn SeverityError\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.severity\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Category returns the declared category, or CategoryServer when undeclared.\n")
+	builder.WriteString("func (e *Error) Category() Category {\n")
+	builder.WriteString("\tif e.category == \"\" {\n")
+	builder.WriteString("\t\treturn CategoryServer\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.category\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// SeverityOf returns the severity of the first *Error in err's chain, or\n")
+	builder.WriteString("// SeverityError when there is none.\n")
+	builder.WriteString("func SeverityOf(err error) Severity {\n")
+	builder.WriteString("\tvar e *Error\n")
+	builder.WriteString("\tif !errors.As(err, &e) || e == nil {\n")
+	builder.WriteString("\t\treturn SeverityError\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.Severity()\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// CategoryOf returns the category of the first *Error in err's chain, or\n")
+	builder.WriteString("// CategoryServer when there is none.\n")
+	builder.WriteString("func CategoryOf(err error) Category {\n")
+	builder.WriteString("\tvar e *Error\n")
+	builder.WriteString("\tif !errors.As(err, &e) || e == nil {\n")
+	builder.WriteString("\t\treturn CategoryServer\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e.Category()\n")
+	builder.WriteString("}\n\n")
+
+	for _, c := range []struct{ name, category string }{
+		{"IsClientError", "CategoryClient"},
+		{"IsServerError", "CategoryServer"},
+		{"IsDependencyError", "CategoryDependency"},
+		{"IsBusinessError", "CategoryBusiness"},
+	} {
+		builder.WriteString(fmt.Sprintf("// %s reports whether CategoryOf(err) is %s.\n", c.name, c.category))
+		builder.WriteString(fmt.Sprintf("func %s(err error) bool {\n", c.name))
+		builder.WriteString(fmt.Sprintf("\treturn CategoryOf(err) == %s\n", c.category))
+		builder.WriteString("}\n\n")
+	}
+}
+
+// writeRetryContent writes the retry classification helpers and Retry.
+func writeRetryContent(builder *strings.Builder) {
+	builder.WriteString("// RetryPolicy holds the backoff hints declared for a retryable error. Zero\n")
+	builder.WriteString("// fields defer to the RetryOptions passed to Retry.\n")
+	builder.WriteString("type RetryPolicy struct {\n")
+	builder.WriteString("\tMaxAttempts    int\n")
+	builder.WriteString("\tInitialBackoff time.Duration\n")
+	builder.WriteString("\tMaxBackoff     time.Duration\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Retryable reports whether the definition allows retrying the operation.\n")
+	builder.WriteString("func (e *Error) Retryable() bool {\n")
+	builder.WriteString("\treturn e.retryable\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// RetryPolicy returns the backoff hints declared for the error.\n")
+	builder.WriteString("func (e *Error) RetryPolicy() RetryPolicy {\n")
+	builder.WriteString("\treturn e.retryPolicy\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// IsRetryable reports whether the first *Error in err's chain is retryable.\n")
+	builder.WriteString("func IsRetryable(err error) bool {\n")
+	builder.WriteString("\tvar e *Error\n")
+	builder.WriteString("\treturn errors.As(err, &e) && e != nil && e.retryable\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// RetryOptions configures Retry. Zero fields use the defaults noted below.\n")
+	builder.WriteString("type RetryOptions struct {\n")
+	builder.WriteString("\tMaxAttempts    int           // used when the error declares none; default 3\n")
+	builder.WriteString("\tInitialBackoff time.Duration // used when the error declares none; default 100ms\n")
+	builder.WriteString("\tMaxBackoff     time.Duration // used when the error declares none; default 5s\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Retry calls fn until it succeeds, returns an error that is not retryable,\n")
+	builder.WriteString("// or reaches the max attempts of the code it last failed with. The backoff\n")
+	builder.WriteString("// doubles after every attempt. If ctx is done while waiting, Retry returns\n")
+	builder.WriteString("// ctx.Err() joined with the last error.\n")
+	builder.WriteString("func Retry(ctx context.Context, fn func(context.Context) error, opts RetryOptions) error {\n")
+	builder.WriteString("\tfor attempt := 1; ; attempt++ {\n")
+	builder.WriteString("\t\terr := fn(ctx)\n")
+	builder.WriteString("\t\tif err == nil {\n")
+	builder.WriteString("\t\t\treturn nil\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t\tvar e *Error\n")
+	builder.WriteString("\t\tif !errors.As(err, &e) || e == nil || !e.retryable {\n")
+	builder.WriteString("\t\t\treturn err\n")
+	builder.WriteString("\t\t}\n\n")
+	builder.WriteString("\t\tpolicy := e.retryPolicy.withDefaults(opts)\n")
+	builder.WriteString("\t\tif attempt >= policy.MaxAttempts {\n")
+	builder.WriteString("\t\t\treturn err\n")
+	builder.WriteString("\t\t}\n\n")
+	builder.WriteString("\t\ttimer := time.NewTimer(policy.backoff(attempt))\n")
+	builder.WriteString("\t\tselect {\n")
+	builder.WriteString("\t\tcase <-ctx.Done():\n")
+	builder.WriteString("\t\t\ttimer.Stop()\n")
+	builder.WriteString("\t\t\treturn errors.Join(ctx.Err(), err)\n")
+	builder.WriteString("\t\tcase <-timer.C:\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// withDefaults fills the zero fields of p from opts and the package defaults.\n")
+	builder.WriteString("func (p RetryPolicy) withDefaults(opts RetryOptions) RetryPolicy {\n")
+	builder.WriteString("\tif p.MaxAttempts == 0 {\n")
+	builder.WriteString("\t\tp.MaxAttempts = cmp.Or(opts.MaxAttempts, 3)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif p.InitialBackoff == 0 {\n")
+	builder.WriteString("\t\tp.InitialBackoff = cmp.Or(opts.InitialBackoff, 100*time.Millisecond)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif p.MaxBackoff == 0 {\n")
+	builder.WriteString("\t\tp.MaxBackoff = cmp.Or(opts.MaxBackoff, 5*time.Second)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn p\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// backoff returns the wait after the given attempt, doubling from\n")
+	builder.WriteString("// InitialBackoff and capped at MaxBackoff.\n")
+	builder.WriteString("func (p RetryPolicy) backoff(attempt int) time.Duration {\n")
+	builder.WriteString("\td := p.InitialBackoff\n")
+	builder.WriteString("\tfor i := 1; i < attempt && d < p.MaxBackoff; i++ {\n")
+	builder.WriteString("\t\td *= 2\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn min(d, p.MaxBackoff)\n")
+	builder.WriteString("}\n\n")
+}
+
+// writeJSONContent writes the JSON wire encoding of Error.
+func writeJSONContent(builder *strings.Builder) {
+	builder.WriteString("// wireError is the JSON wire shape of an Error:\n")
+	builder.WriteString("//\n")
+	builder.WriteString("//\t{\"code\": \"PM0002\", \"domain\": \"payment\", \"msg\": \"...\", \"fields\": {...}, \"wrapped\": {...}}\n")
+	builder.WriteString("//\n")
+	builder.WriteString("// wrapped holds the next *Error in the wrap chain, or {\"msg\": \"...\"} for any\n")
+	builder.WriteString("// other wrapped error. Only code is required.\n")
+	builder.WriteString("type wireError struct {\n")
+	builder.WriteString("\tCode    string         `json:\"code,omitempty\"`\n")
+	builder.WriteString("\tDomain  string         `json:\"domain,omitempty\"`\n")
+	builder.WriteString("\tMsg     string         `json:\"msg,omitempty\"`\n")
+	builder.WriteString("\tFields  map[string]any `json:\"fields,omitempty\"`\n")
+	builder.WriteString("\tWrapped *wireError     `json:\"wrapped,omitempty\"`\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// MarshalJSON encodes e in the wire shape documented on wireError.\n")
+	builder.WriteString("func (e *Error) MarshalJSON() ([]byte, error) {\n")
+	builder.WriteString("\treturn json.Marshal(e.wire())\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("func (e *Error) wire() *wireError {\n")
+	builder.WriteString("\tw := &wireError{\n")
+	builder.WriteString("\t\tCode:   e.Code,\n")
+	builder.WriteString("\t\tDomain: e.Domain,\n")
+	builder.WriteString("\t\tMsg:    e.Msg,\n")
+	builder.WriteString("\t\tFields: e.fields,\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif e.err != nil {\n")
+	builder.WriteString("\t\tvar next *Error\n")
+	builder.WriteString("\t\tif errors.As(e.err, &next) && next != nil {\n")
+	builder.WriteString("\t\t\tw.Wrapped = next.wire()\n")
+	builder.WriteString("\t\t} else {\n")
+	builder.WriteString("\t\t\tw.Wrapped = &wireError{Msg: e.err.Error()}\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn w\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// UnmarshalJSON decodes the wire shape documented on wireError. Known codes\n")
+	builder.WriteString("// resolve to a copy of their generated variable; unknown codes from newer\n")
+	builder.WriteString("// producers are kept as decoded.\n")
+	builder.WriteString("func (e *Error) UnmarshalJSON(data []byte) error {\n")
+	builder.WriteString("\tvar w wireError\n")
+	builder.WriteString("\tif err := json.Unmarshal(data, &w); err != nil {\n")
+	builder.WriteString("\t\treturn err\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif w.Code == \"\" {\n")
+	builder.WriteString("\t\treturn errors.New(\"errz: decode error: missing code\")\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\t*e = *w.decode()\n")
+	builder.WriteString("\treturn nil\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("func (w *wireError) decode() *Error {\n")
+	builder.WriteString("\tvar e Error\n")
+	builder.WriteString("\tif known, ok := Lookup(w.Code); ok {\n")
+	builder.WriteString("\t\te = *known\n")
+	builder.WriteString("\t} else {\n")
+	builder.WriteString("\t\te = Error{Domain: w.Domain, Code: w.Code}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif w.Msg != \"\" {\n")
+	builder.WriteString("\t\te.Msg = w.Msg\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\te.fields = w.Fields\n")
+	builder.WriteString("\tswitch {\n")
+	builder.WriteString("\tcase w.Wrapped == nil:\n")
+	builder.WriteString("\tcase w.Wrapped.Code != \"\":\n")
+	builder.WriteString("\t\te.err = w.Wrapped.decode()\n")
+	builder.WriteString("\tdefault:\n")
+	builder.WriteString("\t\te.err = errors.New(w.Wrapped.Msg)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn &e\n")
+	builder.WriteString("}\n\n")
+}
+
+// writeStackContent writes the opt-in stack capture and the fmt.Formatter
+// implementation that prints it.
+func writeStackContent(builder *strings.Builder) {
+	builder.WriteString("// maxStackDepth bounds the number of frames captured per error.\n")
+	builder.WriteString("const maxStackDepth = 32\n\n")
+
+	builder.WriteString("var stackTraces atomic.Bool\n\n")
+
+	builder.WriteString("// EnableStackTraces turns stack capture in Wrap, WithCause and the New<CODE>\n")
+	builder.WriteString("// constructors on or off. It is off by default, in which case nothing is\n")
+	builder.WriteString("// captured or allocated.\n")
+	builder.WriteString("func EnableStackTraces(enabled bool) {\n")
+	builder.WriteString("\tstackTraces.Store(enabled)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// callers returns the stack of the caller of the exported function that\n")
+	builder.WriteString("// called callers through one unexported helper, or nil when disabled.\n")
+	builder.WriteString("func callers() []uintptr {\n")
+	builder.WriteString("\tif !stackTraces.Load() {\n")
+	builder.WriteString("\t\treturn nil\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tvar pcs [maxStackDepth]uintptr\n")
+	builder.WriteString("\tn := runtime.Callers(4, pcs[:])\n")
+	builder.WriteString("\treturn slices.Clone(pcs[:n])\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// StackTrace returns the frames captured when e was created by Wrap or a\n")
+	builder.WriteString("// New<CODE> constructor, or nil when stack traces were disabled.\n")
+	builder.WriteString("func (e *Error) StackTrace() []runtime.Frame {\n")
+	builder.WriteString("\tif len(e.stack) == 0 {\n")
+	builder.WriteString("\t\treturn nil\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tframes := runtime.CallersFrames(e.stack)\n")
+	builder.WriteString("\tvar out []runtime.Frame\n")
+	builder.WriteString("\tfor {\n")
+	builder.WriteString("\t\tf, more := frames.Next()\n")
+	builder.WriteString("\t\tout = append(out, f)\n")
+	builder.WriteString("\t\tif !more {\n")
+	builder.WriteString("\t\t\treturn out\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Format implements fmt.Formatter. %s and %v print Error(), %q prints it\n")
+	builder.WriteString("// quoted, and %+v also prints the captured stack trace, one frame per line.\n")
+	builder.WriteString("func (e *Error) Format(s fmt.State, verb rune) {\n")
+	builder.WriteString("\tswitch verb {\n")
+	builder.WriteString("\tcase 'v':\n")
+	builder.WriteString("\t\tio.WriteString(s, e.Error())\n")
+	builder.WriteString("\t\tif s.Flag('+') {\n")
+	builder.WriteString("\t\t\tfor _, f := range e.StackTrace() {\n")
+	builder.WriteString("\t\t\t\tfmt.Fprintf(s, \"\\n%s\\n\\t%s:%d\", f.Function, f.File, f.Line)\n")
+	builder.WriteString("\t\t\t}\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\tcase 's':\n")
+	builder.WriteString("\t\tio.WriteString(s, e.Error())\n")
+	builder.WriteString("\tcase 'q':\n")
+	builder.WriteString("\t\tfmt.Fprintf(s, \"%q\", e.Error())\n")
+	builder.WriteString("\tdefault:\n")
+	builder.WriteString("\t\tfmt.Fprintf(s, \"%%!%c(*errz.Error=%s)\", verb, e.Error())\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+}
+
+// writeObserverContent writes the Observer registry together with Record and
+// the notification used by the wrap and constructor paths.
+func writeObserverContent(builder *strings.Builder) {
+	builder.WriteString("// Event tells an Observer why it is notified.\n")
+	builder.WriteString("type Event int\n\n")
+
+	builder.WriteString("const (\n")
+	builder.WriteString("\t// EventCreated is sent by Wrap, WithCause and the New<CODE> constructors,\n")
+	builder.WriteString("\t// with the context given to WrapContext and New<CODE>Context.\n")
+	builder.WriteString("\tEventCreated Event = iota + 1\n")
+	builder.WriteString("\t// EventRecorded is sent by Record and RecordContext.\n")
+	builder.WriteString("\tEventRecorded\n")
+	builder.WriteString(")\n\n")
+
+	builder.WriteString("// Observer is notified whenever an error is created or recorded, e.g. to add\n")
+	builder.WriteString("// its code to the trace span found in ctx.\n")
+	builder.WriteString("//\n")
+	builder.WriteString("// Observers are called synchronously, on the goroutine that created or\n")
+	builder.WriteString("// recorded the error, in the order they were added. They may be called\n")
+	builder.WriteString("// concurrently from many goroutines, must not modify the error and should\n")
+	builder.WriteString("// return quickly. Adding or removing an observer is safe at any time, and\n")
+	builder.WriteString("// takes effect from the next notification.\n")
+	builder.WriteString("type Observer interface {\n")
+	builder.WriteString("\tObserveError(ctx context.Context, event Event, e *Error)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// ObserverFunc adapts a function to the Observer interface.\n")
+	builder.WriteString("type ObserverFunc func(ctx context.Context, event Event, e *Error)\n\n")
+
+	builder.WriteString("// ObserveError calls f(ctx, event, e).\n")
+	builder.WriteString("func (f ObserverFunc) ObserveError(ctx context.Context, event Event, e *Error) {\n")
+	builder.WriteString("\tf(ctx, event, e)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("type observerEntry struct {\n")
+	builder.WriteString("\tid       uint64\n")
+	builder.WriteString("\tobserver Observer\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("var (\n")
+	builder.WriteString("\tobserversMu    sync.Mutex\n")
+	builder.WriteString("\tobserverNextID uint64\n")
+	builder.WriteString("\tobservers      atomic.Pointer[[]observerEntry]\n")
+	builder.WriteString(")\n\n")
+
+	builder.WriteString("// AddObserver registers o and returns a function that removes it.\n")
+	builder.WriteString("func AddObserver(o Observer) (remove func()) {\n")
+	builder.WriteString("\tobserversMu.Lock()\n")
+	builder.WriteString("\tdefer observersMu.Unlock()\n\n")
+	builder.WriteString("\tobserverNextID++\n")
+	builder.WriteString("\tid := observerNextID\n")
+	builder.WriteString("\tvar entries []observerEntry\n")
+	builder.WriteString("\tif current := observers.Load(); current != nil {\n")
+	builder.WriteString("\t\tentries = slices.Clone(*current)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tentries = append(entries, observerEntry{id: id, observer: o})\n")
+	builder.WriteString("\tobservers.Store(&entries)\n\n")
+	builder.WriteString("\treturn func() {\n")
+	builder.WriteString("\t\tobserversMu.Lock()\n")
+	builder.WriteString("\t\tdefer observersMu.Unlock()\n\n")
+	builder.WriteString("\t\tcurrent := observers.Load()\n")
+	builder.WriteString("\t\tif current == nil {\n")
+	builder.WriteString("\t\t\treturn\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t\tentries := slices.DeleteFunc(slices.Clone(*current), func(oe observerEntry) bool {\n")
+	builder.WriteString("\t\t\treturn oe.id == id\n")
+	builder.WriteString("\t\t})\n")
+	builder.WriteString("\t\tif len(entries) == 0 {\n")
+	builder.WriteString("\t\t\tobservers.Store(nil)\n")
+	builder.WriteString("\t\t\treturn\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t\tobservers.Store(&entries)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// OnRecord registers fn to be called with every recorded error. It is a\n")
+	builder.WriteString("// shorthand for an Observer that only handles EventRecorded and, like\n")
+	builder.WriteString("// AddObserver, returns a function that removes it.\n")
+	builder.WriteString("func OnRecord(fn func(*Error)) (remove func()) {\n")
+	builder.WriteString("\treturn AddObserver(ObserverFunc(func(_ context.Context, event Event, e *Error) {\n")
+	builder.WriteString("\t\tif event == EventRecorded {\n")
+	builder.WriteString("\t\t\tfn(e)\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}))\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// notify sends event for e to every observer.\n")
+	builder.WriteString("func notify(ctx context.Context, event Event, e *Error) {\n")
+	builder.WriteString("\tentries := observers.Load()\n")
+	builder.WriteString("\tif entries == nil {\n")
+	builder.WriteString("\t\treturn\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tfor _, oe := range *entries {\n")
+	builder.WriteString("\t\toe.observer.ObserveError(ctx, event, e)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Record is RecordContext with context.Background().\n")
+	builder.WriteString("func Record(err error) {\n")
+	builder.WriteString("\tRecordContext(context.Background(), err)\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// RecordContext reports the first *Error in err's chain to every Observer\n")
+	builder.WriteString("// as EventRecorded, e.g. to count it. Errors without an *Error are ignored.\n")
+	builder.WriteString("func RecordContext(ctx context.Context, err error) {\n")
+	builder.WriteString("\tif observers.Load() == nil {\n")
+	builder.WriteString("\t\treturn\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tvar e *Error\n")
+	builder.WriteString("\tif !errors.As(err, &e) || e == nil {\n")
+	builder.WriteString("\t\treturn\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tnotify(ctx, EventRecorded, e)\n")
+	builder.WriteString("}\n\n")
+}
+
+// writeProblemContent writes the RFC 9457 problem details type together with
+// its Problem and FromProblem conversions.
+func writeProblemContent(builder *strings.Builder) {
+	builder.WriteString("// ProblemContentType is the media type of RFC 9457 problem details.\n")
+	builder.WriteString("const ProblemContentType = \"application/problem+json\"\n\n")
+
+	builder.WriteString("// ProblemTypeBaseURL is prefixed to the error code to build the problem type\n")
+	builder.WriteString("// URI. When empty, problems use \"about:blank\".\n")
+	builder.WriteString("var ProblemTypeBaseURL = \"\"\n\n")
+
+	builder.WriteString("// Problem is the RFC 9457 problem details representation of an Error.\n")
+	builder.WriteString("type Problem struct {\n")
+	builder.WriteString("\tType     string         `json:\"type\"`\n")
+	builder.WriteString("\tTitle    string         `json:\"title\"`\n")
+	builder.WriteString("\tStatus   int            `json:\"status\"`\n")
+	builder.WriteString("\tDetail   string         `json:\"detail,omitempty\"`\n")
+	builder.WriteString("\tInstance string         `json:\"instance,omitempty\"`\n")
+	builder.WriteString("\tCode     string         `json:\"code\"`\n")
+	builder.WriteString("\tDomain   string         `json:\"domain\"`\n")
+	builder.WriteString("\tFields   map[string]any `json:\"fields,omitempty\"`\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// Problem returns the problem details for e. Like Redact, it leaves out the\n")
+	builder.WriteString("// fields of sensitive errors.\n")
+	builder.WriteString("func (e *Error) Problem() Problem {\n")
+	builder.WriteString("\ttyp := \"about:blank\"\n")
+	builder.WriteString("\tif ProblemTypeBaseURL != \"\" {\n")
+	builder.WriteString("\t\ttyp = ProblemTypeBaseURL + e.Code\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tfields := e.Fields()\n")
+	builder.WriteString("\tif e.sensitive {\n")
+	builder.WriteString("\t\tfields = nil\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn Problem{\n")
+	builder.WriteString("\t\tType:   typ,\n")
+	builder.WriteString("\t\tTitle:  e.PublicMessage(),\n")
+	builder.WriteString("\t\tStatus: e.HTTPStatus(),\n")
+	builder.WriteString("\t\tCode:   e.Code,\n")
+	builder.WriteString("\t\tDomain: e.Domain,\n")
+	builder.WriteString("\t\tFields: fields,\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n\n")
+
+	builder.WriteString("// FromProblem decodes problem details and returns the error variable for its\n")
+	builder.WriteString("// code, copied with the decoded fields when there are any. Unknown codes are\n")
+	builder.WriteString("// rebuilt from the problem members so they are not lost.\n")
+	builder.WriteString("func FromProblem(data []byte) (*Error, error) {\n")
+	builder.WriteString("\tvar p Problem\n")
+	builder.WriteString("\tif err := json.Unmarshal(data, &p); err != nil {\n")
+	builder.WriteString("\t\treturn nil, fmt.Errorf(\"errz: decode problem: %w\", err)\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif p.Code == \"\" {\n")
+	builder.WriteString("\t\treturn nil, errors.New(\"errz: problem has no code\")\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\te, ok := Lookup(p.Code)\n")
+	builder.WriteString("\tif !ok {\n")
+	builder.WriteString("\t\te = &Error{\n")
+	builder.WriteString("\t\t\tDomain:     p.Domain,\n")
+	builder.WriteString("\t\t\tCode:       p.Code,\n")
+	builder.WriteString("\t\t\tMsg:        p.Title,\n")
+	builder.WriteString("\t\t\thttpStatus: p.Status,\n")
+	builder.WriteString("\t\t}\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tif len(p.Fields) > 0 {\n")
+	builder.WriteString("\t\tc := *e\n")
+	builder.WriteString("\t\tc.fields = p.Fields\n")
+	builder.WriteString("\t\te = &c\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("\treturn e, nil\n")
+	builder.WriteString("}\n\n")
+}
+
 func escape(s string) string {
 	replacer := strings.NewReplacer(
 		`"`, `\"`,
@@ -149,13 +1156,14 @@ var (
 
 var errInvalidDomainName = errors.New("domain name must be non-empty and alphanumeric")
 
-// generateMarkdownContent builds Markdown content for a given domain and its errors.
-func generateMarkdownContent(domain string, errors map[string]Error) (string, error) {
+// generateMarkdownContent builds Markdown content for a given domain, its errors and its retired codes.
+// The title uses the display name from the domain registry entry when it has one.
+func generateMarkdownContent(domain string, info domainEntry, errors map[string]definition, retired []tombstone) (string, error) {
 	if strings.TrimSpace(domain) == "" || strings.ContainsAny(domain, " ./\\") {
 		return "", errInvalidDomainName
 	}
 
-	if len(errors) == 0 {
+	if len(errors) == 0 && len(retired) == 0 {
 		// TODO: This should use the errLenErrors from above, but it causes a cycle.
 		// For now, create a new error. This should be addressed.
 		return "", fmt.Errorf("no error definitions provided for markdown generation")
@@ -172,32 +1180,105 @@ func generateMarkdownContent(domain string, errors map[string]Error) (string, er
 	// Estimate rough capacity: header + rows + details (~300 bytes per error)
 	builder.Grow(500 + len(codes)*300)
 
-	builder.WriteString(normalizeMarkdownTitle(domain))
+	if info.DisplayName != "" {
+		builder.WriteString(fmt.Sprintf("# %s Errors\n\n", info.DisplayName))
+	} else {
+		builder.WriteString(normalizeMarkdownTitle(domain))
+	}
+	if info.Description != "" {
+		builder.WriteString(info.Description + "\n\n")
+	}
+	if info.Owner != "" {
+		builder.WriteString(fmt.Sprintf("Owner: %s\n\n", info.Owner))
+	}
 
-	// Write Markdown header
-	builder.WriteString("| Code | Message |\n")
-	builder.WriteString("|:-----:|:-----------:|\n")
+	if len(codes) > 0 {
+		// Write Markdown header
+		builder.WriteString("| Code | Message |\n")
+		builder.WriteString("|:-----:|:-----------:|\n")
 
-	// Write each error row
-	for _, code := range codes {
-		errDef := errors[code]
-		builder.WriteString(fmt.Sprintf(
-			"| %s | %s |\n",
-			errDef.Code,
-			escapeMarkdownInline(errDef.Msg),
-		))
-	}
+		// Write each error row, striking through deprecated codes
+		for _, code := range codes {
+			errDef := errors[code]
+			if errDef.Deprecated {
+				builder.WriteString(fmt.Sprintf(
+					"| ~~%s~~ | ~~%s~~%s |\n",
+					errDef.Code,
+					escapeMarkdownInline(errDef.Msg),
+					replacementSuffix(domain, errDef),
+				))
+				continue
+			}
+			builder.WriteString(fmt.Sprintf(
+				"| %s | %s |\n",
+				errDef.Code,
+				escapeMarkdownInline(errDef.Msg),
+			))
+		}
 
-	builder.WriteString("\n---\n\n")
+		builder.WriteString("\n---\n\n")
+	}
 
 	// Full error details
 	for _, code := range codes {
 		errDef := errors[code]
 		builder.WriteString(fmt.Sprintf("## %s\n\n", code))
+		if errDef.Deprecated {
+			builder.WriteString(fmt.Sprintf("- **Deprecated**: yes%s\n", replacementSuffix(domain, errDef)))
+		}
 		builder.WriteString(fmt.Sprintf("- **Domain**: %s\n", errDef.Domain))
 		builder.WriteString(fmt.Sprintf("- **Code**: %s\n", errDef.Code))
 		builder.WriteString(fmt.Sprintf("- **Message**: %s\n", escapeMarkdownBlock(errDef.Msg)))
+		for _, tag := range sortedKeys(errDef.Translations) {
+			builder.WriteString(fmt.Sprintf("- **Message (%s)**: %s\n", tag, escapeMarkdownBlock(errDef.Translations[tag])))
+		}
 		builder.WriteString(fmt.Sprintf("- **Cause**: %s\n", errDef.Cause)) // Cause is already escaped in Go content
+		if errDef.Severity != "" {
+			builder.WriteString(fmt.Sprintf("- **Severity**: %s\n", errDef.Severity))
+		}
+		if errDef.Category != "" {
+			builder.WriteString(fmt.Sprintf("- **Category**: %s\n", errDef.Category))
+		}
+		if errDef.HTTPStatus != 0 {
+			builder.WriteString(fmt.Sprintf("- **HTTP Status**: %d %s\n", errDef.HTTPStatus, http.StatusText(errDef.HTTPStatus)))
+		}
+		if errDef.Retryable {
+			builder.WriteString(fmt.Sprintf("- **Retryable**: %s\n", describeRetry(errDef.Retry)))
+		}
+		if errDef.Sensitive {
+			builder.WriteString("- **Sensitive**: message is replaced for external clients\n")
+		}
+		if len(errDef.Params) > 0 {
+			params := make([]string, 0, len(errDef.Params))
+			for _, p := range errDef.Params {
+				params = append(params, fmt.Sprintf("`%s` (%s)", p.Name, p.Type))
+			}
+			builder.WriteString(fmt.Sprintf("- **Params**: %s\n", strings.Join(params, ", ")))
+		}
+	}
+
+	// Retired codes keep the numbering history visible
+	if len(retired) > 0 {
+		sorted := slices.Clone(retired)
+		slices.SortFunc(sorted, func(a, b tombstone) int {
+			return strings.Compare(a.Code, b.Code)
+		})
+
+		if len(codes) > 0 {
+			builder.WriteString("\n")
+		}
+		builder.WriteString("## Retired codes\n\n")
+		builder.WriteString("These codes are no longer returned and must never be reused.\n\n")
+		builder.WriteString("| Code | Former message | Reason |\n")
+		builder.WriteString("|:-----:|:-----------:|:-----------:|\n")
+		for _, t := range sorted {
+			builder.WriteString(fmt.Sprintf(
+				"| %s | %s | %s |\n",
+				t.Code,
+				escapeMarkdownInline(t.Msg),
+				escapeMarkdownInline(t.Reason),
+			))
+		}
 	}
 
 	output := builder.String()
@@ -206,6 +1287,45 @@ func generateMarkdownContent(domain string, errors map[string]Error) (string, er
 
 }
 
+// replacementSuffix renders " (use [XX0000](link))" for a deprecated definition
+// with a replacement, linking across domain documents when needed.
+func replacementSuffix(domain string, def definition) string {
+	if def.ReplacedBy == "" {
+		return ""
+	}
+
+	anchor := "#" + strings.ToLower(def.ReplacedBy)
+	if def.replacedByDomain != "" && def.replacedByDomain != domain {
+		d := strings.ToLower(def.replacedByDomain)
+		anchor = fmt.Sprintf("../%s/%s.md%s", d, d, anchor)
+	}
+
+	return fmt.Sprintf(" (use [%s](%s))", def.ReplacedBy, anchor)
+}
+
+// describeRetry renders the retry hints of a retryable definition.
+func describeRetry(r *retryHints) string {
+	if r == nil {
+		return "yes"
+	}
+
+	var parts []string
+	if r.MaxAttempts != 0 {
+		parts = append(parts, fmt.Sprintf("max %d attempts", r.MaxAttempts))
+	}
+	if r.BackoffMS != 0 {
+		parts = append(parts, fmt.Sprintf("backoff %s", time.Duration(r.BackoffMS)*time.Millisecond))
+	}
+	if r.MaxBackoffMS != 0 {
+		parts = append(parts, fmt.Sprintf("max backoff %s", time.Duration(r.MaxBackoffMS)*time.Millisecond))
+	}
+	if len(parts) == 0 {
+		return "yes"
+	}
+
+	return "yes (" + strings.Join(parts, ", ") + ")"
+}
+
 // escapeMarkdownInline escapes Markdown inline content (e.g., table cells)
 func escapeMarkdownInline(text string) string {
 	return strings.ReplaceAll(text, "|", "\\|")
@@ -248,7 +1368,7 @@ var (
 	errEmptyDir  = errors.New("directory path cannot be empty")
 )
 
-func writeGoFile(outputPath string, errors map[string]Error) error {
+func writeGoFile(outputPath string, errors map[string]definition) error {
 	if strings.TrimSpace(outputPath) == "" {
 		return errEmptyFile
 	}
@@ -265,7 +1385,7 @@ func writeGoFile(outputPath string, errors map[string]Error) error {
 	return nil
 }
 
-func writeMarkdownFile(outputDirPath, domain string, errors map[string]Error) error {
+func writeMarkdownFile(outputDirPath, domain string, info domainEntry, errors map[string]definition, retired []tombstone) error {
 	if strings.TrimSpace(outputDirPath) == "" {
 		return errEmptyDir
 	}
@@ -278,7 +1398,7 @@ func writeMarkdownFile(outputDirPath, domain string, errors map[string]Error) er
 	}
 
 	filename := filepath.Join(domainDir, fmt.Sprintf("%s.md", domainLower))
-	content, err := generateMarkdownContent(domain, errors)
+	content, err := generateMarkdownContent(domain, info, errors, retired)
 	if err != nil {
 		return fmt.Errorf("failed to generate markdown content: %w", err)
 	}
@@ -1,21 +1,31 @@
 package errz
 
 import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
 )
 
 func TestGenerate_Success(t *testing.T) {
 	tmpDir := t.TempDir()
 	outputGoFile := filepath.Join(tmpDir, "errors_gen.go")
 
-	errors := map[string]Error{
+	errors := map[string]definition{
 		"UR0001": {
 			Domain: "user",
 			Code:   "USER_NOT_FOUND",
@@ -30,7 +40,7 @@ func TestGenerate_Success(t *testing.T) {
 		},
 	}
 
-	err := generate(outputGoFile, tmpDir, errors)
+	err := generate(outputGoFile, tmpDir, errors, nil, nil)
 	if err != nil {
 		t.Fatalf("Generate failed: %v", err)
 	}
@@ -53,32 +63,32 @@ func TestGenerate_Success(t *testing.T) {
 }
 
 func TestGenerate_EmptyOutputPath(t *testing.T) {
-	err := generate("", t.TempDir(), map[string]Error{})
+	err := generate("", t.TempDir(), map[string]definition{}, nil, nil)
 	if err == nil || err.Error() != "failed to write go content: output file path cannot be empty" {
 		t.Errorf("Expected output file path error, got: %v", err)
 	}
 }
 
 func TestGenerate_EmptyMarkdownOutputDir(t *testing.T) {
-	err := generate(t.TempDir()+"/go.go", "", map[string]Error{
+	err := generate(t.TempDir()+"/go.go", "", map[string]definition{
 		"X": {Code: "X", Domain: "abc"},
-	})
+	}, nil, nil)
 	if err == nil || err.Error() == "" {
 		t.Errorf("Expected markdown directory error, got: %v", err)
 	}
 }
 
 func TestGenerate_EmptyDomainInError(t *testing.T) {
-	err := generate(t.TempDir()+"/go.go", t.TempDir()+"/doc", map[string]Error{
+	err := generate(t.TempDir()+"/go.go", t.TempDir()+"/doc", map[string]definition{
 		"X": {Code: "X", Domain: ""},
-	})
+	}, nil, nil)
 	if err == nil || err.Error() == "" {
 		t.Errorf("Expected domain error, got: %v", err)
 	}
 }
 
 func TestGenerateGoContent_Basic(t *testing.T) {
-	defs := map[string]Error{
+	defs := map[string]definition{
 		"TT0001": {
 			Domain: "test",
 			Code:   "TT0001",
@@ -97,7 +107,7 @@ func TestGenerateGoContent_Basic(t *testing.T) {
 }
 
 func TestGenerateGoContent_MultipleErrorsSorted(t *testing.T) {
-	defs := map[string]Error{
+	defs := map[string]definition{
 		"ZE0001": {Code: "ZE0001", Msg: "z"},
 		"AE0001": {Code: "AE0001", Msg: "a"},
 	}
@@ -111,7 +121,7 @@ func TestGenerateGoContent_MultipleErrorsSorted(t *testing.T) {
 }
 
 func TestGenerateGoContent_EscapeCharacters(t *testing.T) {
-	defs := map[string]Error{
+	defs := map[string]definition{
 		"TT0001": {
 			Code: "TT0001",
 			Msg:  `quote " and newline \n`,
@@ -124,14 +134,14 @@ func TestGenerateGoContent_EscapeCharacters(t *testing.T) {
 }
 
 func TestGenerateGoContent_EmptyInput(t *testing.T) {
-	code, err := generateGoContent(map[string]Error{})
+	code, err := generateGoContent(map[string]definition{})
 	assert.Error(t, err)
 	assert.Empty(t, code)
 	assert.EqualError(t, err, "no error definitions provided")
 }
 
 func TestGenerateGoContent_ErrorMethodIncluded(t *testing.T) {
-	defs := map[string]Error{
+	defs := map[string]definition{
 		"XX0001": {
 			Domain: "x",
 			Code:   "XX0001",
@@ -154,7 +164,7 @@ func TestGenerateMarkdownContent_ValidInput(t *testing.T) {
 	titleCacheReset()
 	defer titleCacheReset()
 
-	errorsMap := map[string]Error{
+	errorsMap := map[string]definition{
 		"ERR001": {
 			Code:  "ERR001",
 			Msg:   "Invalid input | bad format",
@@ -167,7 +177,7 @@ func TestGenerateMarkdownContent_ValidInput(t *testing.T) {
 		},
 	}
 
-	md, err := generateMarkdownContent("core-api", errorsMap)
+	md, err := generateMarkdownContent("core-api", domainEntry{}, errorsMap, nil)
 	assert.NoError(t, err)
 	assert.Contains(t, md, "# Core-Api Errors")
 	assert.Contains(t, md, "| ERR001 | Invalid input \\| bad format |")
@@ -180,10 +190,10 @@ func TestGenerateMarkdownContent_InvalidDomain(t *testing.T) {
 	titleCacheReset()
 	defer titleCacheReset()
 
-	_, err := generateMarkdownContent("bad domain", map[string]Error{})
+	_, err := generateMarkdownContent("bad domain", domainEntry{}, map[string]definition{}, nil)
 	assert.ErrorIs(t, err, errInvalidDomainName)
 
-	_, err = generateMarkdownContent(" ", map[string]Error{})
+	_, err = generateMarkdownContent(" ", domainEntry{}, map[string]definition{}, nil)
 	assert.ErrorIs(t, err, errInvalidDomainName)
 }
 
@@ -191,7 +201,7 @@ func TestGenerateMarkdownContent_EmptyErrors(t *testing.T) {
 	titleCacheReset()
 	defer titleCacheReset()
 
-	md, err := generateMarkdownContent("example", map[string]Error{})
+	md, err := generateMarkdownContent("example", domainEntry{}, map[string]definition{}, nil)
 	assert.Error(t, err)
 	assert.Empty(t, md)
 	assert.EqualError(t, err, "no error definitions provided for markdown generation")
@@ -201,12 +211,12 @@ func TestGenerateMarkdownContent_Sorting(t *testing.T) {
 	titleCacheReset()
 	defer titleCacheReset()
 
-	errorsMap := map[string]Error{
+	errorsMap := map[string]definition{
 		"B": {Code: "B"},
 		"A": {Code: "A"},
 	}
 
-	md, err := generateMarkdownContent("domain", errorsMap)
+	md, err := generateMarkdownContent("domain", domainEntry{}, errorsMap, nil)
 	assert.NoError(t, err)
 	firstIdx := strings.Index(md, "## A")
 	secondIdx := strings.Index(md, "## B")
@@ -245,7 +255,7 @@ func TestWriteToFile_EmptyPath(t *testing.T) {
 func TestWriteGoFile_Success(t *testing.T) {
 	tmpFile := filepath.Join(t.TempDir(), "errors.go")
 
-	err := writeGoFile(tmpFile, map[string]Error{
+	err := writeGoFile(tmpFile, map[string]definition{
 		"TEST_CODE": {
 			Code:  "TEST_CODE",
 			Msg:   "This is a test error",
@@ -269,13 +279,13 @@ func TestWriteMarkdownFile_Success(t *testing.T) {
 	tmpDir := t.TempDir()
 	domain := "test-domain"
 
-	err := writeMarkdownFile(tmpDir, domain, map[string]Error{
+	err := writeMarkdownFile(tmpDir, domain, domainEntry{}, map[string]definition{
 		"TEST_MARKDOWN": {
 			Code:  "TEST_MARKDOWN",
 			Msg:   "Markdown message",
 			Cause: "Some cause",
 		},
-	})
+	}, nil)
 
 	require.NoError(t, err)
 
@@ -287,6 +297,531 @@ func TestWriteMarkdownFile_Success(t *testing.T) {
 }
 
 func TestWriteMarkdownFile_EmptyDir(t *testing.T) {
-	err := writeMarkdownFile("", "domain", nil)
+	err := writeMarkdownFile("", "domain", domainEntry{}, nil, nil)
 	require.ErrorIs(t, err, errEmptyDir)
 }
+
+func TestGenerateGoContent_IsAndCodeOfIncluded(t *testing.T) {
+	defs := map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	}
+
+	code, err := generateGoContent(defs)
+	assert.NoError(t, err)
+	assert.Contains(t, code, "func (e *Error) Is(target error) bool")
+	assert.Contains(t, code, "func CodeOf(err error) (string, bool)")
+}
+
+func TestGenerateGoContent_WrapMethodsIncluded(t *testing.T) {
+	defs := map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	}
+
+	code, err := generateGoContent(defs)
+	assert.NoError(t, err)
+	assert.Contains(t, code, "func (e *Error) Wrap(err error) *Error")
+	assert.Contains(t, code, "func (e *Error) WithCause(err error) *Error")
+	assert.Contains(t, code, "func (e *Error) Unwrap() error")
+}
+
+func TestGenerateGoContent_ParamConstructor(t *testing.T) {
+	defs := map[string]definition{
+		"PM0001": {
+			Domain: "payment",
+			Code:   "PM0001",
+			Msg:    "need {required}, have {available}",
+			Cause:  "not enough balance",
+			Params: []param{
+				{Name: "required", Type: "float64"},
+				{Name: "available", Type: "float64"},
+			},
+		},
+		"PM0002": {Domain: "payment", Code: "PM0002", Msg: "timeout", Cause: "gateway"},
+	}
+
+	code, err := generateGoContent(defs)
+	require.NoError(t, err)
+	assert.Contains(t, code, "func NewPM0001(required float64, available float64) *Error {")
+	assert.Contains(t, code, `"required", required,`)
+	assert.NotContains(t, code, "func NewPM0002(")
+
+	_, err = parser.ParseFile(token.NewFileSet(), "errz_gen.go", code, parser.AllErrors)
+	assert.NoError(t, err)
+}
+
+func TestGenerateGoContent_ParamConstructorTypeChecks(t *testing.T) {
+	defs := map[string]definition{
+		"PM0001": {
+			Domain:       "payment",
+			Code:         "PM0001",
+			Msg:          "need {required} {currency}",
+			Cause:        "short by {required} for {user} after {errors} tries (slog {slog})",
+			Translations: map[string]string{"th": "ต้องการ {required} {currency}"},
+			Params: []param{
+				{Name: "required", Type: "float64"},
+				{Name: "currency", Type: "string"},
+				{Name: "user", Type: "any"},
+				{Name: "errors", Type: "int"},
+				{Name: "slog", Type: "bool"},
+			},
+			Deprecated: true,
+		},
+	}
+	require.NoError(t, validatePlaceholders(defs))
+
+	code, err := generateGoContent(defs)
+	require.NoError(t, err)
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "errz_gen.go", code, parser.AllErrors)
+	require.NoError(t, err)
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	_, err = conf.Check("errz", fset, []*ast.File{file}, nil)
+	assert.NoError(t, err)
+}
+
+func TestGenerateMarkdownContent_Params(t *testing.T) {
+	titleCacheReset()
+	defer titleCacheReset()
+
+	md, err := generateMarkdownContent("payment", domainEntry{}, map[string]definition{
+		"PM0001": {
+			Code:   "PM0001",
+			Msg:    "need {required}",
+			Params: []param{{Name: "required", Type: "float64"}},
+		},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, md, "- **Params**: `required` (float64)")
+}
+
+func TestGenerateGoContent_HTTPStatus(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"PM0002": {Domain: "payment", Code: "PM0002", Msg: "timeout", Cause: "gateway", HTTPStatus: 504},
+		"PM0003": {Domain: "payment", Code: "PM0003", Msg: "other", Cause: "other"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "func (e *Error) HTTPStatus() int")
+	assert.Contains(t, code, "httpStatus: 504,")
+	assert.NotContains(t, code, "httpStatus: 0,")
+}
+
+func TestGenerateGoContent_ProblemIncluded(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "func (e *Error) Problem() Problem")
+	assert.Contains(t, code, "func FromProblem(data []byte) (*Error, error)")
+	assert.Contains(t, code, `"XX0001": XX0001,`)
+}
+
+func TestGenerateGoContent_Translations(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"PM0001": {
+			Domain:       "payment",
+			Code:         "PM0001",
+			Msg:          "insufficient balance",
+			Cause:        "not enough balance",
+			Translations: map[string]string{"th": "ยอดเงินไม่พอ", "en-GB": "balance too low"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "func (e *Error) Localize(tags ...string) string")
+	assert.Contains(t, code, "\t\t\t\"en-gb\": \"balance too low\",\n\t\t\t\"th\": \"ยอดเงินไม่พอ\",\n")
+}
+
+func TestGenerateGoContent_Sensitive(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"DB0001": {Domain: "database", Code: "DB0001", Msg: "replica lagging", Cause: "slot full", Sensitive: true},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "sensitive: true,")
+	assert.Contains(t, code, "func Redact(err error) *Error")
+	assert.Contains(t, code, "func (e *Error) PublicMessage() string")
+	assert.Contains(t, code, "func (e *Error) InternalDetail() string")
+}
+
+func TestGenerateGoContent_LogValueIncluded(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "\"log/slog\"")
+	assert.Contains(t, code, "func (e *Error) LogValue() slog.Value")
+}
+
+func TestGenerateGoContent_Retry(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"PM0002": {
+			Domain:    "payment",
+			Code:      "PM0002",
+			Msg:       "timeout",
+			Cause:     "gateway",
+			Retryable: true,
+			Retry:     &retryHints{MaxAttempts: 3, BackoffMS: 200, MaxBackoffMS: 2000},
+		},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "retryable: true,")
+	assert.Contains(t, code, "retryPolicy: RetryPolicy{MaxAttempts: 3, InitialBackoff: 200 * time.Millisecond, MaxBackoff: 2000 * time.Millisecond},")
+	assert.Contains(t, code, "func Retry(ctx context.Context, fn func(context.Context) error, opts RetryOptions) error")
+}
+
+func TestDescribeRetry(t *testing.T) {
+	assert.Equal(t, "yes", describeRetry(nil))
+	assert.Equal(t, "yes (max 3 attempts, backoff 200ms, max backoff 2s)",
+		describeRetry(&retryHints{MaxAttempts: 3, BackoffMS: 200, MaxBackoffMS: 2000}))
+	assert.Equal(t, "yes (max 2 attempts)", describeRetry(&retryHints{MaxAttempts: 2}))
+}
+
+func TestGenerateGoContent_Registry(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"PM0001": {Domain: "payment", Code: "PM0001", Msg: "a", Cause: "a"},
+		"AU0001": {Domain: "auth", Code: "AU0001", Msg: "b", Cause: "b"},
+		"AU0002": {Domain: "auth", Code: "AU0002", Msg: "c", Cause: "c"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "var registry = []*Error{\n\tAU0001,\n\tAU0002,\n\tPM0001,\n}")
+	assert.Contains(t, code, "var domains = []string{\n\t\"auth\",\n\t\"payment\",\n}")
+	assert.Contains(t, code, "func Lookup(code string) (*Error, bool)")
+	assert.Contains(t, code, "func All() iter.Seq[*Error]")
+	assert.Contains(t, code, "func ByDomain(domain string) iter.Seq[*Error]")
+}
+
+func TestGenerateGoContent_FieldsIncluded(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "func (e *Error) With(key string, value any) *Error")
+	assert.Contains(t, code, "func (e *Error) Fields() map[string]any")
+}
+
+func TestGenerateGoContent_JSONIncluded(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "func (e *Error) MarshalJSON() ([]byte, error)")
+	assert.Contains(t, code, "func (e *Error) UnmarshalJSON(data []byte) error")
+}
+
+func TestGenerateGoContent_StackTraceIncluded(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "func EnableStackTraces(enabled bool)")
+	assert.Contains(t, code, "func (e *Error) StackTrace() []runtime.Frame")
+	assert.Contains(t, code, "func (e *Error) Format(s fmt.State, verb rune)")
+}
+
+func TestGenerateGoContent_SeverityAndCategory(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"PM0002": {Domain: "payment", Code: "PM0002", Msg: "timeout", Cause: "gateway", Severity: "critical", Category: "dependency"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "severity: SeverityCritical,")
+	assert.Contains(t, code, "category: CategoryDependency,")
+	assert.Contains(t, code, "func IsClientError(err error) bool")
+	assert.Contains(t, code, "func SeverityOf(err error) Severity")
+
+	_, err = parser.ParseFile(token.NewFileSet(), "errz_gen.go", code, parser.AllErrors)
+	assert.NoError(t, err)
+}
+
+func TestGenerateGoContent_RecordIncluded(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"XX0001": {Domain: "x", Code: "XX0001", Msg: "msg", Cause: "cause"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "func Record(err error)")
+	assert.Contains(t, code, "func OnRecord(fn func(*Error))")
+	assert.Contains(t, code, "func AddObserver(o Observer) (remove func())")
+	assert.Contains(t, code, "func RecordContext(ctx context.Context, err error)")
+}
+
+func TestGenerateGoContent_Deprecated(t *testing.T) {
+	code, err := generateGoContent(map[string]definition{
+		"PM0001": {Domain: "payment", Code: "PM0001", Msg: "old {id}", Cause: "old", Deprecated: true, ReplacedBy: "PM0002", Params: []param{{Name: "id", Type: "string"}}},
+		"PM0002": {Domain: "payment", Code: "PM0002", Msg: "new", Cause: "new"},
+		"PM0003": {Domain: "payment", Code: "PM0003", Msg: "gone", Cause: "gone", Deprecated: true},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, code, "\t// Deprecated: use errz.PM0002 instead.\n\tPM0001 = &Error{")
+	assert.Contains(t, code, "\t// Deprecated: PM0003 is retired and has no replacement.\n\tPM0003 = &Error{")
+	assert.Contains(t, code, "//\n// Deprecated: use errz.PM0002 instead.\nfunc NewPM0001(")
+	assert.NotContains(t, code, "Deprecated: use errz.PM0002 instead.\n\tPM0002")
+}
+
+func TestGenerateMarkdownContent_Deprecated(t *testing.T) {
+	md, err := generateMarkdownContent("payment", domainEntry{}, map[string]definition{
+		"PM0001": {Domain: "payment", Code: "PM0001", Msg: "old", Cause: "old", Deprecated: true, ReplacedBy: "PM0002", replacedByDomain: "payment"},
+		"PM0002": {Domain: "payment", Code: "PM0002", Msg: "new", Cause: "new"},
+		"PM0003": {Domain: "payment", Code: "PM0003", Msg: "moved", Cause: "moved", Deprecated: true, ReplacedBy: "AU0001", replacedByDomain: "auth"},
+	}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, md, "| ~~PM0001~~ | ~~old~~ (use [PM0002](#pm0002)) |")
+	assert.Contains(t, md, "| PM0002 | new |")
+	assert.Contains(t, md, "- **Deprecated**: yes (use [AU0001](../auth/auth.md#au0001))")
+}
+
+func TestGenerateMarkdownContent_Retired(t *testing.T) {
+	md, err := generateMarkdownContent("payment", domainEntry{}, map[string]definition{
+		"PM0001": {Domain: "payment", Code: "PM0001", Msg: "a", Cause: "a"},
+	}, []tombstone{
+		{Domain: "payment", Code: "PM0004", Msg: "b|c"},
+		{Domain: "payment", Code: "PM0003", Msg: "card expired", Reason: "merged into PM0001"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, md, "## Retired codes\n\n")
+	assert.Contains(t, md, "| PM0003 | card expired | merged into PM0001 |\n| PM0004 | b\\|c |  |\n")
+}
+
+func TestGenerateMarkdownContent_OnlyRetired(t *testing.T) {
+	md, err := generateMarkdownContent("payment", domainEntry{}, nil, []tombstone{
+		{Domain: "payment", Code: "PM0003", Msg: "card expired"},
+	})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(md, "# Payment Errors\n\n## Retired codes\n\n"), md)
+	assert.NotContains(t, md, "| Code | Message |")
+}
+
+func TestGenerate_RetiredOnlyDomain(t *testing.T) {
+	docDir := t.TempDir()
+	err := generate(filepath.Join(t.TempDir(), "errors_gen.go"), docDir, map[string]definition{
+		"PM0001": {Domain: "payment", Code: "PM0001", Msg: "a", Cause: "a"},
+	}, map[string]tombstone{
+		"LG0001": {Domain: "legacy", Code: "LG0001", Msg: "gone"},
+	}, nil)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(docDir, "legacy", "legacy.md"))
+}
+
+func TestGenerateMarkdownContent_DomainRegistry(t *testing.T) {
+	md, err := generateMarkdownContent("ext", domainEntry{
+		Domain:      "ext",
+		DisplayName: "External Services",
+		Description: "Failures of third-party services.",
+		Owner:       "integrations",
+	}, map[string]definition{
+		"EX0001": {Domain: "ext", Code: "EX0001", Msg: "a", Cause: "a"},
+	}, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(md, "# External Services Errors\n\nFailures of third-party services.\n\nOwner: integrations\n\n| Code | Message |"), md)
+}
+
+func TestGenerator_Run_DomainRegistry(t *testing.T) {
+	outDir := t.TempDir()
+	g := Generator{
+		SchemaPath:        "testdata/error_schema.json",
+		DefinitionsDir:    "testdata/valid",
+		OutputPath:        filepath.Join(outDir, "errz_gen.go"),
+		OutputDocDir:      filepath.Join(outDir, "docs"),
+		DomainsPath:       "testdata/domains.json",
+		DomainsSchemaPath: "testdata/domains_schema.json",
+	}
+	require.NoError(t, g.Run())
+
+	md, err := os.ReadFile(filepath.Join(outDir, "docs", "common", "common.md"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(md), "# Common Errors\n\n"))
+
+	g.DefinitionsDir = "testdata/unregistered_prefix"
+	err = g.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "domain registry")
+}
+
+func TestGenerator_Run_ReportsAllFailures(t *testing.T) {
+	outDir := t.TempDir()
+	g := Generator{
+		SchemaPath:     "testdata/error_schema.json",
+		DefinitionsDir: "testdata/broken",
+		OutputPath:     filepath.Join(outDir, "errz_gen.go"),
+		OutputDocDir:   filepath.Join(outDir, "docs"),
+	}
+	err := g.Run()
+	require.Error(t, err)
+
+	msg := err.Error()
+	auth := strings.Index(msg, `testdata/broken/auth.json:4:5: AU0001: key does not match code "AU0002"`)
+	common := strings.Index(msg, "testdata/broken/common.json:2:3: CM0001: cause is required")
+	payment := strings.Index(msg, "testdata/broken/payment.json:5:5: invalid character")
+	assert.True(t, auth >= 0 && common > auth && payment > common, msg)
+	assert.Contains(t, msg, "^\n\ntestdata/broken/common.json:2:3:", "files are separated by a blank line")
+
+	var ds Diagnostics
+	require.True(t, errors.As(err, &ds))
+	var rules []string
+	for _, d := range ds {
+		if d.File == "testdata/broken/common.json" {
+			assert.Equal(t, "CM0001", d.Code)
+		}
+		rules = append(rules, d.Rule)
+	}
+	assert.Subset(t, rules, []string{RuleKeyMismatch, "schema/required", RuleSyntax})
+
+	var d *Diagnostic
+	require.True(t, errors.As(err, &d))
+	assert.NotEmpty(t, d.Message)
+	assert.NoFileExists(t, g.OutputPath)
+}
+
+func TestGenerator_Validate(t *testing.T) {
+	g := Generator{
+		SchemaPath:     "testdata/error_schema.json",
+		DefinitionsDir: "testdata/broken",
+	}
+	ds := g.Validate()
+	require.True(t, ds.HasErrors())
+
+	i := slices.IndexFunc(ds, func(d *Diagnostic) bool { return d.Rule == RuleKeyMismatch })
+	require.GreaterOrEqual(t, i, 0, ds.Error())
+	assert.Equal(t, "testdata/broken/auth.json", ds[i].File)
+	assert.Equal(t, "AU0001", ds[i].Code)
+	assert.Equal(t, "code", ds[i].Field)
+	assert.Equal(t, Position{Line: 4, Column: 5}, ds[i].Position)
+	assert.Equal(t, DiagnosticError, ds[i].Severity)
+	assert.Equal(t, `key does not match code "AU0002"`, ds[i].Message)
+
+	i = slices.IndexFunc(ds, func(d *Diagnostic) bool { return d.Rule == "schema/required" })
+	require.GreaterOrEqual(t, i, 0, ds.Error())
+	assert.Equal(t, "CM0001", ds[i].Code)
+	assert.Equal(t, "cause is required", ds[i].Message)
+
+	g.DefinitionsDir = "testdata/valid"
+	assert.Empty(t, g.Validate())
+}
+
+// writeCatalog writes a valid catalog of files definitions files holding
+// perFile codes each, every file under its own prefix, and returns its
+// directory.
+func writeCatalog(tb testing.TB, files, perFile int) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	for f := range files {
+		prefix := string([]byte{'A' + byte(f/26), 'A' + byte(f%26)})
+		domain := "domain" + strings.ToLower(prefix)
+
+		var b strings.Builder
+		b.WriteString("{\n")
+		for i := range perFile {
+			code := fmt.Sprintf("%s%04d", prefix, i+1)
+			if i > 0 {
+				b.WriteString(",\n")
+			}
+			fmt.Fprintf(&b, "  %q: {\n", code)
+			fmt.Fprintf(&b, "    \"domain\": %q,\n", domain)
+			fmt.Fprintf(&b, "    \"code\": %q,\n", code)
+			fmt.Fprintf(&b, "    \"msg\": \"failure %s: {id}\",\n", code)
+			b.WriteString("    \"cause\": \"generated for benchmarks\",\n")
+			b.WriteString("    \"http_status\": 400,\n")
+			b.WriteString("    \"params\": [{ \"name\": \"id\", \"type\": \"string\" }]\n")
+			b.WriteString("  }")
+		}
+		b.WriteString("\n}\n")
+
+		require.NoError(tb, os.WriteFile(filepath.Join(dir, domain+".json"), []byte(b.String()), 0644))
+	}
+	return dir
+}
+
+func TestWriteCatalog(t *testing.T) {
+	g := Generator{
+		SchemaPath:     "testdata/error_schema.json",
+		DefinitionsDir: writeCatalog(t, 3, 5),
+	}
+	assert.Empty(t, g.Validate())
+}
+
+// BenchmarkGenerator_Validate measures the single pass: the schema is
+// compiled once and each file is read once, then validated and loaded.
+func BenchmarkGenerator_Validate(b *testing.B) {
+	g := Generator{
+		SchemaPath:     "testdata/error_schema.json",
+		DefinitionsDir: writeCatalog(b, 40, 100),
+	}
+	for b.Loop() {
+		if ds := g.Validate(); len(ds) > 0 {
+			b.Fatal(ds)
+		}
+	}
+}
+
+// BenchmarkValidateThenLoad measures a copy of the two passes the single pass
+// replaced, kept here as the baseline: every file is validated sequentially
+// against a freshly compiled schema, while an unbounded goroutine per file
+// reads it again to load it.
+func BenchmarkValidateThenLoad(b *testing.B) {
+	dir := writeCatalog(b, 40, 100)
+	for b.Loop() {
+		if err := validateThenLoad("testdata/error_schema.json", dir); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func validateThenLoad(schemaPath, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var paths []string
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) == ".json" {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	var schemaErr error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		schemaAbs, _ := filepath.Abs(schemaPath)
+		for _, path := range paths {
+			abs, _ := filepath.Abs(path)
+			result, err := gojsonschema.Validate(
+				gojsonschema.NewReferenceLoader("file:///"+filepath.ToSlash(schemaAbs)),
+				gojsonschema.NewReferenceLoader("file:///"+filepath.ToSlash(abs)),
+			)
+			if err != nil {
+				schemaErr = err
+				return
+			}
+			if !result.Valid() {
+				schemaErr = fmt.Errorf("%s: %v", path, result.Errors())
+				return
+			}
+		}
+	}()
+
+	defs := make(map[string]definition)
+	var loadErrs []error
+	var mu sync.Mutex
+	for _, path := range paths {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := os.ReadFile(path)
+			if err == nil {
+				var fileDefs map[string]definition
+				fileDefs, _, err = decodeDefinitions(newSourceFile(path, content))
+				mu.Lock()
+				maps.Copy(defs, fileDefs)
+				mu.Unlock()
+			}
+			if err != nil {
+				mu.Lock()
+				loadErrs = append(loadErrs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errors.Join(schemaErr, errors.Join(loadErrs...), validateDefinitions(defs, nil))
+}
@@ -5,7 +5,6 @@ go 1.24.3
 require (
 	github.com/stretchr/testify v1.10.0
 	github.com/xeipuuv/gojsonschema v1.2.0
-	golang.org/x/sync v0.14.0
 )
 
 require (
@@ -0,0 +1,152 @@
+// Package httperr renders errz errors as JSON HTTP responses. Every error
+// written is reported through errz.RecordContext, with the request context
+// for the Localized variants and the given one for the Context variants, and
+// then passed through errz.Redact, so causes and wrapped errors never reach
+// the client.
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unlimited-budget-ecommerce/errz"
+)
+
+// Response is the JSON body written by Write.
+type Response struct {
+	Code    string         `json:"code"`
+	Domain  string         `json:"domain"`
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+// Write renders err as a JSON response using the HTTP status declared for its
+// code. Errors that do not carry an *errz.Error are reported as errz.CM0500.
+func Write(w http.ResponseWriter, err error) {
+	e := resolve(err)
+	writeResponse(w, e, e.PublicMessage())
+}
+
+// WriteContext is like Write but records err with ctx, so observers can reach
+// the trace span of the request.
+func WriteContext(ctx context.Context, w http.ResponseWriter, err error) {
+	e := resolveContext(ctx, err)
+	writeResponse(w, e, e.PublicMessage())
+}
+
+// WriteLocalized is like Write but translates the message into the language
+// preferred by the request's Accept-Language header.
+func WriteLocalized(w http.ResponseWriter, r *http.Request, err error) {
+	e := resolveContext(r.Context(), err)
+	writeResponse(w, e, e.Localize(AcceptLanguage(r)...))
+}
+
+// WriteProblem renders err as an RFC 9457 application/problem+json response.
+// Errors that do not carry an *errz.Error are reported as errz.CM0500.
+func WriteProblem(w http.ResponseWriter, err error) {
+	e := resolve(err)
+	writeProblem(w, e, e.Problem())
+}
+
+// WriteProblemContext is like WriteProblem but records err with ctx, so
+// observers can reach the trace span of the request.
+func WriteProblemContext(ctx context.Context, w http.ResponseWriter, err error) {
+	e := resolveContext(ctx, err)
+	writeProblem(w, e, e.Problem())
+}
+
+// WriteProblemLocalized is like WriteProblem but translates the title into the
+// language preferred by the request's Accept-Language header.
+func WriteProblemLocalized(w http.ResponseWriter, r *http.Request, err error) {
+	e := resolveContext(r.Context(), err)
+	p := e.Problem()
+	p.Title = e.Localize(AcceptLanguage(r)...)
+	writeProblem(w, e, p)
+}
+
+// AcceptLanguage returns the language tags of the request's Accept-Language
+// header ordered by descending quality. Tags with q=0 and the "*" wildcard are
+// dropped.
+func AcceptLanguage(r *http.Request) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var prefs []weighted
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+
+		prefs = append(prefs, weighted{tag: tag, q: q})
+	}
+
+	sort.SliceStable(prefs, func(i, j int) bool {
+		return prefs[i].q > prefs[j].q
+	})
+
+	tags := make([]string, len(prefs))
+	for i, p := range prefs {
+		tags[i] = p.tag
+	}
+
+	return tags
+}
+
+// resolve is resolveContext with context.Background().
+func resolve(err error) *errz.Error {
+	return resolveContext(context.Background(), err)
+}
+
+// resolveContext records err and returns a redacted copy of the *errz.Error it
+// carries. Errors without one are wrapped in errz.CM0500 first.
+func resolveContext(ctx context.Context, err error) *errz.Error {
+	var e *errz.Error
+	if !errors.As(err, &e) || e == nil {
+		err = errz.CM0500.Wrap(err)
+	}
+
+	errz.RecordContext(ctx, err)
+	return errz.Redact(err)
+}
+
+func writeResponse(w http.ResponseWriter, e *errz.Error, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.HTTPStatus())
+
+	_ = json.NewEncoder(w).Encode(Response{
+		Code:    e.Code,
+		Domain:  e.Domain,
+		Message: msg,
+		Fields:  e.Fields(),
+	})
+}
+
+func writeProblem(w http.ResponseWriter, e *errz.Error, p errz.Problem) {
+	w.Header().Set("Content-Type", errz.ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(e.HTTPStatus())
+
+	_ = json.NewEncoder(w).Encode(p)
+}
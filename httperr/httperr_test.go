@@ -0,0 +1,129 @@
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unlimited-budget-ecommerce/errz"
+)
+
+func TestWrite_ErrzError(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Write(rec, fmt.Errorf("charge: %w", errz.PM0002.Wrap(errors.New("i/o timeout"))))
+
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var body Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, Response{Code: "PM0002", Domain: "payment", Message: "payment gateway timeout"}, body)
+}
+
+func TestWrite_NonErrzFallsBackToCM0500(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Write(rec, errors.New("database is on fire"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	var body Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "CM0500", body.Code)
+	assert.NotContains(t, rec.Body.String(), "database is on fire")
+}
+
+func TestWriteProblem(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	WriteProblem(rec, errz.PM0001)
+
+	assert.Equal(t, http.StatusPaymentRequired, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	got, err := errz.FromProblem(rec.Body.Bytes())
+	require.NoError(t, err)
+	assert.Same(t, errz.PM0001, got)
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "en;q=0.8, th-TH, *;q=0.1, fr;q=0, de;q=abc")
+
+	assert.Equal(t, []string{"th-TH", "en"}, AcceptLanguage(r))
+
+	assert.Empty(t, AcceptLanguage(httptest.NewRequest(http.MethodGet, "/", nil)))
+}
+
+func TestWriteLocalized(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "th-TH,th;q=0.9,en;q=0.8")
+	rec := httptest.NewRecorder()
+
+	WriteLocalized(rec, r, errz.PM0001)
+
+	var body Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, "ยอดเงินคงเหลือไม่เพียงพอ", body.Message)
+}
+
+func TestWriteProblemLocalized(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr")
+	rec := httptest.NewRecorder()
+
+	WriteProblemLocalized(rec, r, errz.PM0001)
+
+	var p errz.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.Equal(t, "insufficient balance", p.Title)
+}
+
+type ctxKey struct{}
+
+func TestWriteContext_RecordsWithContext(t *testing.T) {
+	var spans []any
+	remove := errz.AddObserver(errz.ObserverFunc(func(ctx context.Context, event errz.Event, e *errz.Error) {
+		if event == errz.EventRecorded {
+			spans = append(spans, ctx.Value(ctxKey{}))
+		}
+	}))
+	defer remove()
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "span-1")
+	rec := httptest.NewRecorder()
+	WriteContext(ctx, rec, errz.PM0001)
+	assert.Equal(t, http.StatusPaymentRequired, rec.Code)
+
+	rec = httptest.NewRecorder()
+	WriteProblemContext(ctx, rec, errz.PM0002)
+	assert.Equal(t, errz.ProblemContentType, rec.Header().Get("Content-Type"))
+
+	assert.Equal(t, []any{"span-1", "span-1"}, spans)
+}
+
+func TestWrite_DoesNotLeakCause(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Write(rec, errz.PM0002.Wrap(errors.New("dial tcp 10.0.0.7:443")))
+
+	assert.NotContains(t, rec.Body.String(), "10.0.0.7")
+	assert.NotContains(t, rec.Body.String(), errz.PM0002.Cause)
+}
+
+func TestWrite_IncludesFields(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	Write(rec, errz.PM0001.With("order_id", "OD-42"))
+
+	var body Response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, map[string]any{"order_id": "OD-42"}, body.Fields)
+}
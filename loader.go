@@ -1,65 +1,352 @@
 package errz
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 
-	"golang.org/x/sync/errgroup"
+	"github.com/xeipuuv/gojsonschema"
 )
 
-// loadErrorDefinitions loads all JSON files from a directory and returns combined error definitions map.
-func loadErrorDefinitions(dir string) (map[string]Error, error) {
-	result := make(map[string]Error)
-	var mu sync.Mutex
+// definition is a single error entry as declared in the definitions JSON.
+type definition struct {
+	Domain       string            `json:"domain"`
+	Code         string            `json:"code"`
+	Msg          string            `json:"msg"`
+	Cause        string            `json:"cause"`
+	HTTPStatus   int               `json:"http_status,omitempty"`
+	Severity     string            `json:"severity,omitempty"`
+	Category     string            `json:"category,omitempty"`
+	Retryable    bool              `json:"retryable,omitempty"`
+	Retry        *retryHints       `json:"retry,omitempty"`
+	Deprecated   bool              `json:"deprecated,omitempty"`
+	ReplacedBy   string            `json:"replaced_by,omitempty"`
+	Sensitive    bool              `json:"sensitive,omitempty"`
+	Translations map[string]string `json:"translations,omitempty"`
+	Params       []param           `json:"params,omitempty"`
 
+	// loc is the key of the entry in the definitions file it was loaded from.
+	loc location
+	// replacedByDomain is the domain of ReplacedBy, resolved before the
+	// markdown is rendered so cross-domain links can be built.
+	replacedByDomain string
+}
+
+// param declares a {placeholder} used in msg or cause and its Go type.
+type param struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// retryHints are the optional backoff hints of a retryable definition.
+type retryHints struct {
+	MaxAttempts  int `json:"max_attempts,omitempty"`
+	BackoffMS    int `json:"backoff_ms,omitempty"`
+	MaxBackoffMS int `json:"max_backoff_ms,omitempty"`
+}
+
+// tombstone records a code that was removed from the definitions. Its number
+// can never be given to a new definition.
+type tombstone struct {
+	Domain string `json:"domain"`
+	Code   string `json:"code"`
+	Msg    string `json:"msg"`
+	Reason string `json:"reason,omitempty"`
+
+	// loc is the tombstone's element in the retired list it was loaded from.
+	loc location
+}
+
+// domainEntry is a prefix registered in the domain registry (domains.json).
+type domainEntry struct {
+	Domain      string `json:"domain"`
+	DisplayName string `json:"display_name"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"`
+
+	// loc is the prefix's key in the registry it was loaded from.
+	loc location
+}
+
+// loadDomains loads the domain registry, keyed by code prefix. When schema is
+// not nil, the registry is validated against it first.
+func loadDomains(path string, schema *gojsonschema.Schema) (map[string]domainEntry, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read error at %s: %w", path, err)
+	}
+
+	src := newSourceFile(path, content)
+	src.keyedByPrefix = true
+
+	if schema != nil {
+		if err := validateDocument(schema, src); err != nil {
+			return nil, fmt.Errorf("validation failed for %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	var domains map[string]domainEntry
+	if err := json.Unmarshal(content, &domains); err != nil {
+		return nil, syntaxError(src, err)
+	}
+
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no domains found in %s", path)
+	}
+
+	for prefix, entry := range domains {
+		entry.loc = location{src: src}.field(prefix)
+		domains[prefix] = entry
+	}
+
+	return domains, nil
+}
+
+// retiredKey is the top-level key holding the tombstones of a definitions file.
+const retiredKey = "retired"
+
+// loadAndValidateDefinitions loads all JSON files from a directory in a single pass and returns
+// the combined error definitions map along with the retired codes: each file is read once,
+// validated against schema when it is not nil, decoded and merged. An active definition reusing
+// a retired code is an error. Every file is loaded even when others fail: the error joins the
+// failures of all files, and the returned maps hold whatever could be loaded so later checks can
+// still run on it. At most workers files are processed at once; zero means runtime.GOMAXPROCS(0).
+func loadAndValidateDefinitions(dir string, schema *gojsonschema.Schema, workers int) (map[string]definition, map[string]tombstone, error) {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, err
+		return nil, nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
 	}
 
-	var g errgroup.Group
+	var paths []string
 	for _, entry := range entries {
-		entry := entry
 		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
 			continue
 		}
+		paths = append(paths, filepath.Join(dir, entry.Name()))
+	}
 
-		fullPath := filepath.Join(dir, entry.Name())
+	type fileResult struct {
+		defs       map[string]definition
+		tombstones []tombstone
+		err        error
+	}
+	results := make([]fileResult, len(paths))
 
-		g.Go(func() error {
-			content, err := os.ReadFile(fullPath)
-			if err != nil {
-				return fmt.Errorf("read error at %s: %w", fullPath, err)
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(paths)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				r := &results[i]
+				r.defs, r.tombstones, r.err = loadDefinitionsFile(paths[i], schema)
 			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
 
-			var defs map[string]Error
-			if err := json.Unmarshal(content, &defs); err != nil {
-				return fmt.Errorf("unmarshal error at %s: %w", fullPath, err)
+	// Merge in directory order, so that "first defined" always names the
+	// same file.
+	result := make(map[string]definition)
+	retired := make(map[string]tombstone)
+	var errs []error
+	for _, r := range results {
+		if r.err != nil {
+			errs = append(errs, r.err)
+		}
+		for _, k := range sortedCodes(r.defs) {
+			v := r.defs[k]
+			if first, exists := result[k]; exists {
+				errs = append(errs, v.loc.errorf(RuleDuplicateCode, "duplicate error code detected, first defined at %s", first.loc))
+				continue
+			}
+			result[k] = v
+		}
+		for _, t := range r.tombstones {
+			if first, exists := retired[t.Code]; exists {
+				d := t.loc.errorf(RuleRetiredCode, "duplicate retired code detected, first retired at %s", first.loc)
+				d.Code = t.Code
+				errs = append(errs, d)
+				continue
 			}
+			retired[t.Code] = t
+		}
+	}
+
+	for _, code := range sortedCodes(result) {
+		if t, ok := retired[code]; ok {
+			errs = append(errs, codeErrorf(result[code].loc, RuleRetiredCode, code, "code is retired at %s and cannot be reused", t.loc))
+		}
+	}
+
+	return result, retired, errors.Join(errs...)
+}
 
-			if len(defs) == 0 {
-				return fmt.Errorf("no errors found in %s", fullPath)
+// loadDefinitionsFile reads and decodes a single definitions file, validating
+// it against schema first when schema is not nil. Definitions that decode are
+// returned even when the file violates the schema.
+func loadDefinitionsFile(path string, schema *gojsonschema.Schema) (map[string]definition, []tombstone, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, &Diagnostic{
+			File:     path,
+			Rule:     RuleRead,
+			Message:  fmt.Sprintf("read error at %s: %v", path, err),
+			Severity: DiagnosticError,
+			err:      err,
+		}
+	}
+	src := newSourceFile(path, content)
+
+	var schemaErr error
+	if schema != nil {
+		if err := validateDocument(schema, src); err != nil {
+			schemaErr = fmt.Errorf("validation failed for %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	defs, tombstones, err := decodeDefinitions(src)
+	if err != nil {
+		return defs, tombstones, errors.Join(schemaErr, err)
+	}
+
+	if len(defs) == 0 && len(tombstones) == 0 {
+		return nil, nil, errors.Join(schemaErr, &Diagnostic{
+			File:     path,
+			Rule:     RuleEmptyFile,
+			Message:  fmt.Sprintf("no errors found in %s", path),
+			Severity: DiagnosticError,
+		})
+	}
+
+	return defs, tombstones, schemaErr
+}
+
+// decodeDefinitions splits a definitions file into its definitions, keyed by
+// code, and the tombstones listed under retiredKey. The top-level object is
+// read token by token so that a key declared twice, which encoding/json would
+// silently overwrite, is reported with both positions; the first declaration
+// is kept and returned with the error so that later checks still run on it.
+// Errors are positioned in src.
+func decodeDefinitions(src *sourceFile) (map[string]definition, []tombstone, error) {
+	dec := json.NewDecoder(bytes.NewReader(src.content))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, syntaxError(src, err)
+	}
+	if tok == nil {
+		// A null document declares nothing.
+		return nil, nil, nil
+	}
+	if tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("unmarshal error at %w", location{src: src}.errorf(RuleSyntax, "definitions must be a JSON object"))
+	}
+
+	defs := make(map[string]definition)
+	var tombstones []tombstone
+	var errs []error
+	seen := make(map[string]int) // key -> offset of its first declaration
+	for dec.More() {
+		start := skipSeparators(src.content, int(dec.InputOffset()))
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, syntaxError(src, err)
+		}
+		key, _ := tok.(string)
+
+		if first, ok := seen[key]; ok {
+			d := src.errorAt(start, RuleDuplicateKey, "duplicate key %q, first declared at %s", key, src.positionString(first))
+			if key != retiredKey {
+				d.Code = key
+				d.Message = "duplicate key, first declared at " + src.positionString(first)
+			}
+			errs = append(errs, d)
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, nil, syntaxError(src, err)
 			}
+			continue
+		}
+		seen[key] = start
 
-			mu.Lock()
-			defer mu.Unlock()
-			for k, v := range defs {
-				if _, exists := result[k]; exists {
-					return fmt.Errorf("duplicate error code detected: %s in %s", k, fullPath)
-				}
-				result[k] = v
+		loc := location{src: src}.field(key)
+		if key == retiredKey {
+			if err := dec.Decode(&tombstones); err != nil {
+				return nil, nil, unmarshalError(loc, err)
+			}
+			for i := range tombstones {
+				tombstones[i].loc = loc.field(strconv.Itoa(i))
 			}
+			continue
+		}
 
-			return nil
-		})
+		def := definition{loc: loc}
+		if err := dec.Decode(&def); err != nil {
+			return nil, nil, unmarshalError(loc, err)
+		}
+		defs[key] = def
 	}
 
-	if err := g.Wait(); err != nil {
-		return nil, err
+	// Consume the closing brace.
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, syntaxError(src, err)
 	}
 
-	return result, nil
+	return defs, tombstones, errors.Join(errs...)
+}
+
+// syntaxError positions an error returned while reading the tokens of src.
+func syntaxError(src *sourceFile, err error) error {
+	var d *Diagnostic
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxErr):
+		// Offset is just past the byte that could not be parsed.
+		d = src.errorAt(max(int(syntaxErr.Offset)-1, 0), RuleSyntax, "%v", err)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		d = src.errorAt(len(src.content), RuleSyntax, "unexpected end of JSON input")
+	default:
+		d = location{src: src}.errorf(RuleSyntax, "%v", err)
+	}
+	d.err = err
+
+	return fmt.Errorf("unmarshal error at %w", d)
+}
+
+// unmarshalError positions err, returned while decoding the value at loc, at
+// the member it names when it is a type error.
+func unmarshalError(loc location, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		if typeErr.Field != "" {
+			for _, name := range strings.Split(typeErr.Field, ".") {
+				loc = loc.field(name)
+			}
+		}
+	case loc.src != nil:
+		return syntaxError(loc.src, err)
+	}
+
+	d := loc.errorf(RuleSyntax, "%v", err)
+	d.err = err
+
+	return fmt.Errorf("unmarshal error at %w", d)
 }
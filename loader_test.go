@@ -3,44 +3,46 @@ package errz
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestLoadErrorDefinitions_Valid(t *testing.T) {
-	defs, err := loadErrorDefinitions("testdata/valid")
+func TestLoadAndValidateDefinitions_Valid(t *testing.T) {
+	defs, _, err := loadAndValidateDefinitions("testdata/valid", nil, 0)
 	assert.NoError(t, err)
 	assert.GreaterOrEqual(t, len(defs), 1)
 	assert.Contains(t, defs, "CM0000")
 }
 
-func TestLoadErrorDefinitions_DirNotFound(t *testing.T) {
-	_, err := loadErrorDefinitions("testdata/does_not_exist")
+func TestLoadAndValidateDefinitions_DirNotFound(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/does_not_exist", nil, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no such file or directory")
 }
 
-func TestLoadErrorDefinitions_EmptyFile(t *testing.T) {
-	_, err := loadErrorDefinitions("testdata/empty_file")
+func TestLoadAndValidateDefinitions_EmptyFile(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/empty_file", nil, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unmarshal error at")
 }
 
-func TestLoadErrorDefinitions_InvalidJSON(t *testing.T) {
-	_, err := loadErrorDefinitions("testdata/invalid_json")
+func TestLoadAndValidateDefinitions_InvalidJSON(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/invalid_json", nil, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unmarshal error")
 }
 
-func TestLoadErrorDefinitions_SkipNonJSONFiles(t *testing.T) {
-	defs, err := loadErrorDefinitions("testdata/mixed_with_nonjson")
+func TestLoadAndValidateDefinitions_SkipNonJSONFiles(t *testing.T) {
+	defs, _, err := loadAndValidateDefinitions("testdata/mixed_with_nonjson", nil, 0)
 	assert.NoError(t, err)
 	assert.Contains(t, defs, "CM0002")
 	assert.NotContains(t, defs, "FAKECODE")
 }
 
-func TestLoadErrorDefinitions_UnreadableFile(t *testing.T) {
+func TestLoadAndValidateDefinitions_UnreadableFile(t *testing.T) {
 	// Create a file and lock the permissions.
 	dir := t.TempDir()
 	file := filepath.Join(dir, "bad.json")
@@ -49,13 +51,154 @@ func TestLoadErrorDefinitions_UnreadableFile(t *testing.T) {
 
 	defer os.Remove(file)
 
-	_, err = loadErrorDefinitions(dir)
+	_, _, err = loadAndValidateDefinitions(dir, nil, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "read error")
 }
 
-func TestLoadErrorDefinitions_DuplicateKey(t *testing.T) {
-	_, err := loadErrorDefinitions("testdata/dup_key")
+func TestLoadAndValidateDefinitions_DuplicateKey(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/dup_key", nil, 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "duplicate error code detected")
 }
+
+func TestLoadAndValidateDefinitions_Retired(t *testing.T) {
+	defs, retired, err := loadAndValidateDefinitions("testdata/retired", nil, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, defs, "PM0001")
+	assert.NotContains(t, defs, "retired")
+	got := retired["PM0003"]
+	assert.Equal(t, "testdata/retired/payment.json:9:5", got.loc.String())
+	got.loc = location{}
+	assert.Equal(t, tombstone{Domain: "payment", Code: "PM0003", Msg: "card expired", Reason: "merged into PM0001"}, got)
+}
+
+func TestLoadAndValidateDefinitions_RetiredCodeReused(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/retired_reused", nil, 0)
+	assert.EqualError(t, err, `testdata/retired_reused/wallet.json:2:3: PM0003: code is retired at testdata/retired_reused/payment.json:3:5 and cannot be reused
+  2 |   "PM0003": {
+    |   ^`)
+}
+
+func TestLoadDomains(t *testing.T) {
+	domains, err := loadDomains("testdata/domains.json", nil)
+	assert.NoError(t, err)
+	common := domains["CM"]
+	assert.Equal(t, "testdata/domains.json:2:3", common.loc.String())
+	common.loc = location{}
+	assert.Equal(t, domainEntry{
+		Domain:      "common",
+		DisplayName: "Common",
+		Description: "Generic outcomes shared by every service.",
+		Owner:       "platform",
+	}, common)
+	assert.Equal(t, "payment", domains["PM"].Domain)
+}
+
+func TestLoadDomains_FileNotFound(t *testing.T) {
+	_, err := loadDomains("testdata/does_not_exist.json", nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "read error")
+}
+
+func TestLoadDomains_SyntaxErrorWithSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.json")
+	require.NoError(t, os.WriteFile(path, []byte("{\n  \"PM\" {}\n}"), 0644))
+
+	_, err := loadDomains(path, mustCompileSchema(t, "testdata/domains_schema.json"))
+	assert.ErrorContains(t, err, "unmarshal error at "+path+":2:8: invalid character '{' after object key")
+}
+
+func TestLoadAndValidateDefinitions_DuplicateKeyInFile(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/dup_in_file", nil, 0)
+	assert.EqualError(t, err, `testdata/dup_in_file/payment.json:14:3: PM0001: duplicate key, first declared at testdata/dup_in_file/payment.json:2:3
+  14 |   "PM0001": {
+     |   ^`)
+}
+
+func TestLoadAndValidateDefinitions(t *testing.T) {
+	schema, err := compileSchema("testdata/error_schema.json")
+	require.NoError(t, err)
+
+	for _, workers := range []int{0, 1, 8} {
+		defs, _, err := loadAndValidateDefinitions("testdata/broken", schema, workers)
+		require.Error(t, err)
+
+		ds := newDiagnostics(err)
+		rules := make([]string, len(ds))
+		for i, d := range ds {
+			rules[i] = d.Rule
+		}
+		// The syntax error of payment.json is reported once, and common.json is
+		// loaded even though it violates the schema.
+		assert.ElementsMatch(t, []string{"schema/required", RuleSyntax}, rules, ds.Error())
+		assert.Contains(t, defs, "AU0001")
+		assert.Contains(t, defs, "CM0001")
+	}
+}
+
+func TestLoadAndValidateDefinitions_DuplicateIsDeterministic(t *testing.T) {
+	dir := t.TempDir()
+	def := `{"PM0001": {"domain": "payment", "code": "PM0001", "msg": "m", "cause": "c"}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.json"), []byte(def), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.json"), []byte(def), 0644))
+
+	for range 10 {
+		_, _, err := loadAndValidateDefinitions(dir, nil, 2)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "b.json:1:2: PM0001: duplicate error code detected, first defined at "+filepath.Join(dir, "a.json"))
+	}
+}
+
+func TestLoadAndValidateDefinitions_DuplicateKeyKeepsFirst(t *testing.T) {
+	defs, _, err := loadAndValidateDefinitions("testdata/dup_in_file", nil, 0)
+	require.Error(t, err)
+
+	var d *Diagnostic
+	require.ErrorAs(t, err, &d)
+	assert.Equal(t, RuleDuplicateKey, d.Rule)
+	assert.Equal(t, "PM0001", d.Code)
+
+	assert.Equal(t, "insufficient balance", defs["PM0001"].Msg)
+	assert.Contains(t, defs, "PM0002")
+}
+
+func TestGenerator_Validate_DuplicateKeyWithSemanticErrors(t *testing.T) {
+	g := Generator{
+		SchemaPath:     "testdata/error_schema.json",
+		DefinitionsDir: "testdata/dup_and_mismatch",
+	}
+	ds := g.Validate()
+
+	rules := make([]string, len(ds))
+	for i, d := range ds {
+		rules[i] = d.Rule
+	}
+	assert.ElementsMatch(t, []string{RuleKeyMismatch, RulePlaceholder, RuleDuplicateKey}, rules, ds.Error())
+}
+
+func TestDecodeDefinitions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "null", content: `null`},
+		{name: "not an object", content: `[]`, wantErr: "unmarshal error at x.json:1:1: definitions must be a JSON object"},
+		{name: "truncated", content: `{"PM0001": {`, wantErr: "unmarshal error at x.json:1:13: unexpected end of JSON input"},
+		{name: "syntax", content: `{"PM0001" {}}`, wantErr: "unmarshal error at x.json:1:11: invalid character '{' after object key"},
+		{name: "type", content: `{"PM0001": {"http_status": "402"}}`, wantErr: "unmarshal error at x.json:1:13: PM0001: json: cannot unmarshal string into Go struct field definition.http_status of type int"},
+		{name: "duplicate retired", content: `{"retired": [], "retired": []}`, wantErr: `x.json:1:17: duplicate key "retired", first declared at x.json:1:2`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := decodeDefinitions(newSourceFile("x.json", []byte(tt.content)))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.wantErr, strings.SplitN(err.Error(), "\n", 2)[0])
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package metrics counts errz errors by code and domain without a metrics
+// vendor. Importing it registers Default with errz.OnRecord, until Stop is
+// called, and publishes its counters through expvar under "errz"; Handler
+// serves them in the Prometheus text exposition format.
+package metrics
+
+import (
+	"cmp"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/unlimited-budget-ecommerce/errz"
+)
+
+// Default is the Collector fed by errz.Record.
+var Default = New()
+
+// removeDefault detaches Default from errz.Record.
+var removeDefault func()
+
+func init() {
+	removeDefault = errz.OnRecord(Default.Observe)
+	expvar.Publish("errz", expvar.Func(Default.expvarValue))
+}
+
+// Stop detaches Default from errz.Record. Its counters keep their values and
+// are still served by Handler and expvar. Calling Stop more than once is a
+// no-op.
+func Stop() {
+	removeDefault()
+}
+
+// Handler serves the counters of Default in the Prometheus text format.
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// key identifies a counter.
+type key struct {
+	code   string
+	domain string
+}
+
+// Collector counts observed errors by code and domain. It is safe for
+// concurrent use; once a code has been seen, counting it is lock-free.
+type Collector struct {
+	counters sync.Map // key -> *atomic.Uint64
+}
+
+// New returns an empty Collector.
+func New() *Collector {
+	return &Collector{}
+}
+
+// Observe counts e once.
+func (c *Collector) Observe(e *errz.Error) {
+	k := key{code: e.Code, domain: e.Domain}
+	counter, ok := c.counters.Load(k)
+	if !ok {
+		counter, _ = c.counters.LoadOrStore(k, new(atomic.Uint64))
+	}
+	counter.(*atomic.Uint64).Add(1)
+}
+
+// Count is the number of times a code was observed.
+type Count struct {
+	Code   string
+	Domain string
+	Value  uint64
+}
+
+// Counts returns a snapshot of every counter ordered by code.
+func (c *Collector) Counts() []Count {
+	var counts []Count
+	c.counters.Range(func(k, v any) bool {
+		key := k.(key)
+		counts = append(counts, Count{Code: key.code, Domain: key.domain, Value: v.(*atomic.Uint64).Load()})
+		return true
+	})
+
+	slices.SortFunc(counts, func(a, b Count) int {
+		return cmp.Or(strings.Compare(a.Code, b.Code), strings.Compare(a.Domain, b.Domain))
+	})
+
+	return counts
+}
+
+// ByCode returns a snapshot of the counters summed by code.
+func (c *Collector) ByCode() map[string]uint64 {
+	out := make(map[string]uint64)
+	for _, count := range c.Counts() {
+		out[count.Code] += count.Value
+	}
+	return out
+}
+
+// ByDomain returns a snapshot of the counters summed by domain.
+func (c *Collector) ByDomain() map[string]uint64 {
+	out := make(map[string]uint64)
+	for _, count := range c.Counts() {
+		out[count.Domain] += count.Value
+	}
+	return out
+}
+
+func (c *Collector) expvarValue() any {
+	return map[string]map[string]uint64{
+		"by_code":   c.ByCode(),
+		"by_domain": c.ByDomain(),
+	}
+}
+
+// Handler serves the counters in the Prometheus text exposition format.
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = c.WritePrometheus(w)
+	})
+}
+
+// WritePrometheus writes the counters in the Prometheus text exposition
+// format.
+func (c *Collector) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# HELP errz_errors_total Errors recorded through errz.Record by code and domain.\n")
+	b.WriteString("# TYPE errz_errors_total counter\n")
+	for _, count := range c.Counts() {
+		fmt.Fprintf(&b, "errz_errors_total{code=\"%s\",domain=\"%s\"} %d\n",
+			escapeLabel(count.Code), escapeLabel(count.Domain), count.Value)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,99 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unlimited-budget-ecommerce/errz"
+	"github.com/unlimited-budget-ecommerce/errz/httperr"
+)
+
+func TestCollector_ConcurrentObserve(t *testing.T) {
+	c := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Observe(errz.PM0001)
+				c.Observe(errz.PM0002.Wrap(errors.New("timeout")))
+				c.Observe(errz.AU0001)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]uint64{"AU0001": 50000, "PM0001": 50000, "PM0002": 50000}, c.ByCode())
+	assert.Equal(t, map[string]uint64{"auth": 50000, "payment": 100000}, c.ByDomain())
+}
+
+func TestCollector_WritePrometheus(t *testing.T) {
+	c := New()
+	c.Observe(errz.PM0002)
+	c.Observe(errz.AU0001)
+	c.Observe(errz.PM0002)
+	c.Observe(&errz.Error{Code: "XX0001", Domain: `we"ird\`})
+
+	var b strings.Builder
+	require.NoError(t, c.WritePrometheus(&b))
+
+	assert.Equal(t, `# HELP errz_errors_total Errors recorded through errz.Record by code and domain.
+# TYPE errz_errors_total counter
+errz_errors_total{code="AU0001",domain="auth"} 1
+errz_errors_total{code="PM0002",domain="payment"} 2
+errz_errors_total{code="XX0001",domain="we\"ird\\"} 1
+`, b.String())
+}
+
+func TestCollector_Handler(t *testing.T) {
+	c := New()
+	c.Observe(errz.CM0400)
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "text/plain; version=0.0.4")
+	assert.Contains(t, rec.Body.String(), `errz_errors_total{code="CM0400",domain="common"} 1`)
+}
+
+func TestDefault_FedByRecordAndHTTPResponder(t *testing.T) {
+	before := Default.ByCode()
+
+	errz.Record(errz.PM0001)
+	errz.Record(errors.New("not errz"))
+	httperr.Write(httptest.NewRecorder(), errz.PM0001)
+	httperr.Write(httptest.NewRecorder(), errors.New("boom"))
+
+	after := Default.ByCode()
+	assert.Equal(t, before["PM0001"]+2, after["PM0001"])
+	assert.Equal(t, before["CM0500"]+1, after["CM0500"])
+
+	var published map[string]map[string]uint64
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("errz").String()), &published))
+	assert.Equal(t, after["PM0001"], published["by_code"]["PM0001"])
+	assert.Equal(t, Default.ByDomain()["payment"], published["by_domain"]["payment"])
+}
+
+func TestStop_DetachesDefault(t *testing.T) {
+	defer func() { removeDefault = errz.OnRecord(Default.Observe) }()
+
+	errz.Record(errz.PM0001)
+	before := Default.ByCode()["PM0001"]
+
+	Stop()
+	Stop()
+	errz.Record(errz.PM0001)
+
+	assert.Equal(t, before, Default.ByCode()["PM0001"])
+}
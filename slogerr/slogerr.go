@@ -0,0 +1,109 @@
+// Package slogerr provides a slog.Handler that logs errz errors as structured
+// attributes, whatever error value they are wrapped in.
+package slogerr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/unlimited-budget-ecommerce/errz"
+)
+
+// Handler wraps another slog.Handler and rewrites every error-valued attribute
+// that carries an *errz.Error into a group holding the error text together
+// with the errz code, domain, msg and cause. Records carrying an errz error are
+// raised to the level of that error's severity, never lowered.
+type Handler struct {
+	next slog.Handler
+}
+
+// NewHandler returns a Handler that forwards to next.
+func NewHandler(next slog.Handler) *Handler {
+	return &Handler{next: next}
+}
+
+// Enabled reports whether the wrapped handler handles records at level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle lifts errz attributes out of r's error-valued attributes and passes
+// the rewritten record to the wrapped handler. When r carries an errz error,
+// its level is raised to the severity level of the first one found if that is
+// higher. Since slog.Logger checks Enabled with the original level first, a
+// record below the level of the logger's handler never reaches Handle.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var first *errz.Error
+	var lifted []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		lifted = append(lifted, liftAttr(a, &first))
+		return true
+	})
+
+	level := r.Level
+	if first != nil {
+		level = max(level, first.Severity().Level())
+	}
+
+	out := slog.NewRecord(r.Time, level, r.Message, r.PC)
+	out.AddAttrs(lifted...)
+
+	return h.next.Handle(ctx, out)
+}
+
+// WithAttrs returns a Handler whose attributes are rewritten like record
+// attributes.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	lifted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		lifted[i] = liftAttr(a, nil)
+	}
+
+	return &Handler{next: h.next.WithAttrs(lifted)}
+}
+
+// WithGroup returns a Handler that opens group on the wrapped handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{next: h.next.WithGroup(name)}
+}
+
+// liftAttr rewrites a into an errz group when its value is an error carrying
+// an *errz.Error, descending into groups. If first is non-nil and still unset,
+// it receives the first *errz.Error found.
+func liftAttr(a slog.Attr, first **errz.Error) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		group := a.Value.Group()
+		lifted := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			lifted[i] = liftAttr(ga, first)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(lifted...)}
+
+	case slog.KindAny, slog.KindLogValuer:
+		err, ok := a.Value.Any().(error)
+		if !ok {
+			return a
+		}
+
+		var e *errz.Error
+		if !errors.As(err, &e) || e == nil {
+			return a
+		}
+		if first != nil && *first == nil {
+			*first = e
+		}
+
+		// The full error text already includes the wrapped error.
+		attrs := []slog.Attr{slog.String("error", err.Error())}
+		for _, ea := range e.LogValue().Group() {
+			if ea.Key != "error" {
+				attrs = append(attrs, ea)
+			}
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}
+	}
+
+	return a
+}
@@ -0,0 +1,93 @@
+package slogerr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/unlimited-budget-ecommerce/errz"
+)
+
+func newLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(NewHandler(slog.NewJSONHandler(buf, nil)))
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+	return out
+}
+
+func TestHandler_LiftsWrappedErrzError(t *testing.T) {
+	var buf bytes.Buffer
+	err := fmt.Errorf("charge: %w", errz.PM0002.Wrap(errors.New("i/o timeout")))
+
+	newLogger(&buf).Error("payment failed", "err", err)
+
+	got := decode(t, &buf)["err"].(map[string]any)
+	assert.Equal(t, "PM0002", got["code"])
+	assert.Equal(t, "payment", got["domain"])
+	assert.Equal(t, "payment gateway timeout", got["msg"])
+	assert.Equal(t, "no response from payment gateway", got["cause"])
+	assert.Equal(t, err.Error(), got["error"])
+}
+
+func TestHandler_LeavesOtherAttrsAlone(t *testing.T) {
+	var buf bytes.Buffer
+
+	newLogger(&buf).Info("hello", "err", errors.New("plain"), "n", 1)
+
+	got := decode(t, &buf)
+	assert.Equal(t, "plain", got["err"])
+	assert.Equal(t, float64(1), got["n"])
+}
+
+func TestHandler_WithAttrsAndGroups(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := newLogger(&buf).With("first", errz.AU0001).WithGroup("req")
+	logger.Warn("nested", slog.Group("inner", "err", fmt.Errorf("x: %w", errz.CM0400)))
+
+	got := decode(t, &buf)
+	assert.Equal(t, "AU0001", got["first"].(map[string]any)["code"])
+
+	inner := got["req"].(map[string]any)["inner"].(map[string]any)
+	assert.Equal(t, "CM0400", inner["err"].(map[string]any)["code"])
+}
+
+func TestHandler_LevelFromSeverity(t *testing.T) {
+	var buf bytes.Buffer
+
+	newLogger(&buf).Info("gateway down", "err", fmt.Errorf("charge: %w", errz.PM0002))
+
+	got := decode(t, &buf)
+	assert.Equal(t, "ERROR", got["level"])
+	assert.Equal(t, "error", got["err"].(map[string]any)["severity"])
+	assert.Equal(t, "dependency", got["err"].(map[string]any)["category"])
+}
+
+func TestHandler_KeepsHigherCallerLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelError})))
+
+	logger.Error("insufficient balance", "err", errz.PM0001)
+
+	got := decode(t, &buf)
+	assert.Equal(t, "ERROR", got["level"])
+	assert.Equal(t, "warning", got["err"].(map[string]any)["severity"])
+}
+
+func TestHandler_KeepsLevelWithoutErrzError(t *testing.T) {
+	var buf bytes.Buffer
+
+	newLogger(&buf).Warn("plain", "err", errors.New("plain"))
+
+	assert.Equal(t, "WARN", decode(t, &buf)["level"])
+}
@@ -0,0 +1,233 @@
+package errz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// sourceFile is a loaded JSON file kept so that diagnostics can point at the
+// offending member. The pointer index is only built when first needed.
+type sourceFile struct {
+	path    string
+	content []byte
+	// keyedByPrefix is set for the domain registry, whose top-level keys are
+	// code prefixes rather than codes.
+	keyedByPrefix bool
+
+	once    sync.Once
+	offsets map[string]int // JSON pointer -> offset of the member key or element
+}
+
+func newSourceFile(path string, content []byte) *sourceFile {
+	return &sourceFile{path: path, content: content}
+}
+
+// offset returns the byte offset of the member or element addressed by the
+// JSON pointer. Unknown pointers fall back to their closest known parent.
+func (f *sourceFile) offset(pointer string) int {
+	f.once.Do(func() {
+		f.offsets = indexJSON(f.content)
+	})
+
+	for {
+		if off, ok := f.offsets[pointer]; ok {
+			return off
+		}
+		i := strings.LastIndexByte(pointer, '/')
+		if i < 0 {
+			return skipSeparators(f.content, 0)
+		}
+		pointer = pointer[:i]
+	}
+}
+
+// position returns the 1-based line and byte column of offset.
+func (f *sourceFile) position(offset int) (line, col int) {
+	offset = min(offset, len(f.content))
+	before := f.content[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = offset - (bytes.LastIndexByte(before, '\n') + 1) + 1
+
+	return line, col
+}
+
+// positionString returns offset as "file:line:col".
+func (f *sourceFile) positionString(offset int) string {
+	line, col := f.position(offset)
+	return fmt.Sprintf("%s:%d:%d", f.path, line, col)
+}
+
+// errorAt returns a diagnostic positioned at offset.
+func (f *sourceFile) errorAt(offset int, rule, format string, args ...any) *Diagnostic {
+	line, col := f.position(offset)
+	return &Diagnostic{
+		File:     f.path,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		Position: Position{Line: line, Column: col},
+		Severity: DiagnosticError,
+		excerpt:  f.excerpt(line, col),
+	}
+}
+
+// excerpt renders the source line and a caret under col:
+//
+//	4 |     "code": "PM0002",
+//	  |     ^
+func (f *sourceFile) excerpt(line, col int) string {
+	lines := bytes.Split(f.content, []byte("\n"))
+	if line > len(lines) {
+		return ""
+	}
+	text := strings.TrimRight(string(lines[line-1]), "\r")
+
+	// Keep tabs so the caret lines up with the source in any tab width.
+	var pad strings.Builder
+	prefix := text[:min(col-1, len(text))]
+	for len(prefix) > 0 {
+		r, size := utf8.DecodeRuneInString(prefix)
+		if r == '\t' {
+			pad.WriteByte('\t')
+		} else {
+			pad.WriteByte(' ')
+		}
+		prefix = prefix[size:]
+	}
+
+	gutter := strconv.Itoa(line)
+	return fmt.Sprintf("  %s | %s\n  %s | %s^", gutter, text, strings.Repeat(" ", len(gutter)), pad.String())
+}
+
+// location addresses a member of a sourceFile by JSON pointer. The zero
+// location is used for definitions built in memory.
+type location struct {
+	src     *sourceFile
+	pointer string
+}
+
+// field returns the location of the named member of l.
+func (l location) field(name string) location {
+	l.pointer += "/" + escapePointer(name)
+	return l
+}
+
+// errorf returns a diagnostic positioned at l, without a position when l has
+// no source file. Its code and field are taken from the pointer.
+func (l location) errorf(rule, format string, args ...any) *Diagnostic {
+	var d *Diagnostic
+	if l.src == nil {
+		d = &Diagnostic{Rule: rule, Message: fmt.Sprintf(format, args...), Severity: DiagnosticError}
+	} else {
+		d = l.src.errorAt(l.src.offset(l.pointer), rule, format, args...)
+	}
+	d.Code, d.Field = l.codeAndField()
+	return d
+}
+
+// codeAndField splits the pointer of a location inside a definition into the
+// definition's code and the dotted path of the member. Locations inside the
+// retired list or the domain registry, or without a source file, have no code.
+func (l location) codeAndField() (code, field string) {
+	if l.src == nil || l.pointer == "" {
+		return "", ""
+	}
+	tokens := strings.Split(l.pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = pointerUnescaper.Replace(t)
+	}
+	if tokens[0] == retiredKey || l.src != nil && l.src.keyedByPrefix {
+		return "", strings.Join(tokens, ".")
+	}
+	return tokens[0], strings.Join(tokens[1:], ".")
+}
+
+// String returns "file:line:col", or "" when l has no source file.
+func (l location) String() string {
+	if l.src == nil {
+		return ""
+	}
+	return l.src.positionString(l.src.offset(l.pointer))
+}
+
+// indexJSON maps the JSON pointer of every member and element in content to
+// the offset where it starts: the opening quote of its key for object
+// members, and of its first declaration for duplicate keys. Invalid JSON
+// yields the offsets found before the error.
+func indexJSON(content []byte) map[string]int {
+	offsets := map[string]int{"": skipSeparators(content, 0)}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	_ = indexValue(dec, content, "", offsets)
+
+	return offsets
+}
+
+func indexValue(dec *json.Decoder, content []byte, pointer string, offsets map[string]int) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for dec.More() {
+			start := skipSeparators(content, int(dec.InputOffset()))
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			child := pointer + "/" + escapePointer(key)
+			if _, dup := offsets[child]; !dup {
+				offsets[child] = start
+			}
+			if err := indexValue(dec, content, child, offsets); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			child := pointer + "/" + strconv.Itoa(i)
+			offsets[child] = skipSeparators(content, int(dec.InputOffset()))
+			if err := indexValue(dec, content, child, offsets); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Consume the closing delimiter.
+	_, err = dec.Token()
+	return err
+}
+
+// skipSeparators returns the offset of the first byte at or after off that
+// is not whitespace, a comma or a colon.
+func skipSeparators(content []byte, off int) int {
+	for off < len(content) {
+		switch content[off] {
+		case ' ', '\t', '\r', '\n', ',', ':':
+			off++
+		default:
+			return off
+		}
+	}
+	return off
+}
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// escapePointer escapes a key for use as a JSON pointer reference token.
+func escapePointer(key string) string {
+	return pointerEscaper.Replace(key)
+}
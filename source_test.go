@@ -0,0 +1,100 @@
+package errz
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sourceSample = `{
+  "PM0001": {
+    "code": "PM0001",
+    "params": [{ "name": "a" }, { "name": "b" }],
+    "translations": { "th": "ยอด", "a/b": "x" }
+  }
+}`
+
+func TestIndexJSON(t *testing.T) {
+	offsets := indexJSON([]byte(sourceSample))
+	assert.Equal(t, 0, offsets[""])
+	assert.Equal(t, 4, offsets["/PM0001"])
+	assert.Equal(t, 20, offsets["/PM0001/code"])
+	assert.Equal(t, 70, offsets["/PM0001/params/1"])
+	assert.Contains(t, offsets, "/PM0001/translations/a~1b")
+}
+
+func TestIndexJSON_Invalid(t *testing.T) {
+	offsets := indexJSON([]byte(`{"PM0001": {"code": `))
+	assert.Contains(t, offsets, "/PM0001/code")
+}
+
+func TestSourceFile_Offset_FallsBackToParent(t *testing.T) {
+	src := newSourceFile("x.json", []byte(sourceSample))
+	assert.Equal(t, 20, src.offset("/PM0001/code/nope"))
+	assert.Equal(t, 0, src.offset("/missing"))
+}
+
+func TestSourceFile_Position(t *testing.T) {
+	src := newSourceFile("x.json", []byte(sourceSample))
+	line, col := src.position(src.offset("/PM0001/params/1"))
+	assert.Equal(t, 4, line)
+	assert.Equal(t, 33, col)
+}
+
+func TestLocation_Errorf(t *testing.T) {
+	src := newSourceFile("x.json", []byte("{\n\t\"PM0001\": {\"msg\": \"ยอด\", \"code\": 1}\n}"))
+	loc := location{src: src}.field("PM0001").field("code")
+
+	assert.Equal(t, "x.json:2:33", loc.String())
+	assert.EqualError(t, loc.errorf(RuleSyntax, "bad %s", "code"), "x.json:2:33: PM0001: bad code\n"+
+		"  2 | \t\"PM0001\": {\"msg\": \"ยอด\", \"code\": 1}\n"+
+		"    | \t                         ^")
+}
+
+func TestLocation_Errorf_NoSource(t *testing.T) {
+	var loc location
+	assert.Equal(t, "", loc.field("code").String())
+	d := loc.field("code").errorf(RuleSyntax, "bad")
+	assert.EqualError(t, d, "bad")
+	assert.False(t, d.Position.IsValid())
+}
+
+func TestLocation_Errorf_CodeAndField(t *testing.T) {
+	src := newSourceFile("x.json", []byte(sourceSample))
+	d := location{src: src}.field("PM0001").field("params").field("1").field("name").errorf(RuleParam, "bad")
+	assert.Equal(t, "x.json", d.File)
+	assert.Equal(t, "PM0001", d.Code)
+	assert.Equal(t, "params.1.name", d.Field)
+	assert.Equal(t, RuleParam, d.Rule)
+	assert.Equal(t, Position{Line: 4, Column: 35}, d.Position)
+
+	d = location{src: src}.field(retiredKey).field("0").errorf(RuleRetiredCode, "bad")
+	assert.Empty(t, d.Code)
+	assert.Equal(t, "retired.0", d.Field)
+}
+
+func TestValidateDocument_Positions(t *testing.T) {
+	err := validateFile(t, "testdata/error_schema.json", "testdata/invalid/invalid_extra_field.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "testdata/invalid/invalid_extra_field.json:7:5: CM0001: Additional property extra_field is not allowed\n"+
+		"  7 |     \"extra_field\": \"not allowed\"\n"+
+		"    |     ^")
+
+	err = validateFile(t, "testdata/error_schema.json", "testdata/invalid/invalid_param_type.json")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "testdata/invalid/invalid_param_type.json:7:38: PM0001: params.0.type:")
+
+	var d *Diagnostic
+	require.ErrorAs(t, err, &d)
+	assert.Equal(t, "PM0001", d.Code)
+	assert.Equal(t, "params.0.type", d.Field)
+	assert.Equal(t, "schema/enum", d.Rule)
+	assert.Equal(t, Position{Line: 7, Column: 38}, d.Position)
+}
+
+func TestLoadAndValidateDefinitions_Positions(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/invalid_json", nil, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unmarshal error at testdata/invalid_json/invalid_missing_required.json:1:3: invalid: json: cannot unmarshal")
+}
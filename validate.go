@@ -1,67 +1,92 @@
 package errz
 
 import (
+	"errors"
 	"fmt"
+	"go/token"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/xeipuuv/gojsonschema"
 )
 
-// validateAllJSONFiles validates all JSON files in a directory against the schema.
-func validateAllJSONFiles(schemaPath, dir string) error {
-	entries, err := os.ReadDir(dir)
+// compileSchema loads and compiles the JSON Schema at path, so that it can
+// validate any number of documents.
+func compileSchema(path string) (*gojsonschema.Schema, error) {
+	loader, err := loadFileAsReferenceLoader(path)
 	if err != nil {
-		return fmt.Errorf("failed to read directory %s: %w", dir, err)
+		return nil, fmt.Errorf("cannot load schema %s: %w", path, err)
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
-			continue
-		}
-
-		jsonPath := filepath.Join(dir, entry.Name())
-		if err := validateJSON(schemaPath, jsonPath); err != nil {
-			return fmt.Errorf("validation failed for %s: %w", entry.Name(), err)
-		}
+	schema, err := gojsonschema.NewSchema(loader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile schema %s: %w", path, err)
 	}
 
-	return nil
+	return schema, nil
 }
 
-// validateJSON validates a JSON file against a JSON Schema located at schemaPath.
-// If validation fails, it returns a detailed error message with all issues found.
-func validateJSON(schemaPath, jsonPath string) error {
-	schemaLoader, err := loadFileAsReferenceLoader(schemaPath)
+// validateDocument validates the content of src against schema. Every
+// violation is positioned at the offending member of src. Content that is not
+// valid JSON is left to the decoder that follows, which reports the syntax
+// error with its position.
+func validateDocument(schema *gojsonschema.Schema, src *sourceFile) error {
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(src.content))
 	if err != nil {
-		return fmt.Errorf("cannot load schema %s: %w", schemaPath, err)
+		return nil
 	}
 
-	documentLoader, err := loadFileAsReferenceLoader(jsonPath)
-	if err != nil {
-		return fmt.Errorf("cannot load JSON file %s: %w", jsonPath, err)
-	}
+	if !result.Valid() {
+		errs := make([]error, 0, len(result.Errors()))
+		for _, e := range result.Errors() {
+			errs = append(errs, schemaError(src, e))
+		}
 
-	result, err := gojsonschema.Validate(schemaLoader, documentLoader)
-	if err != nil {
-		return fmt.Errorf("failed to run validation: %w", err)
+		return fmt.Errorf("JSON validation failed:\n%w", errors.Join(errs...))
 	}
 
-	if !result.Valid() {
-		var builder strings.Builder
-		builder.WriteString("JSON validation failed:\n")
+	return nil
+}
 
-		for _, e := range result.Errors() {
-			builder.WriteString("- ")
-			builder.WriteString(e.String())
-			builder.WriteRune('\n')
+// schemaError returns the diagnostic for a schema violation in src. The code
+// is dropped from the front of the message, since Diagnostic.Error renders it.
+func schemaError(src *sourceFile, e gojsonschema.ResultError) *Diagnostic {
+	d := location{src: src, pointer: schemaErrorPointer(e)}.errorf("schema/"+e.Type(), "%s", e.String())
+	if d.Code != "" {
+		// gojsonschema renders "PM0001: msg" or "PM0001.field: msg".
+		msg := strings.TrimPrefix(d.Message, d.Code)
+		if rest, ok := strings.CutPrefix(msg, ": "); ok {
+			d.Message = rest
+		} else if rest, ok := strings.CutPrefix(msg, "."); ok {
+			d.Message = rest
 		}
+	}
 
-		return fmt.Errorf("%s", builder.String())
+	return d
+}
+
+// schemaErrorPointer returns the JSON pointer of the member a schema error is
+// about. Errors about an unexpected property point at that property rather
+// than at the object holding it.
+func schemaErrorPointer(e gojsonschema.ResultError) string {
+	var pointer strings.Builder
+	// The context starts with "(root)"; a NUL delimiter keeps keys containing
+	// dots intact.
+	for _, token := range strings.Split(e.Context().String("\x00"), "\x00")[1:] {
+		pointer.WriteString("/" + escapePointer(token))
+	}
+	if e.Type() == "additional_property_not_allowed" {
+		if property, ok := e.Details()["property"].(string); ok {
+			pointer.WriteString("/" + escapePointer(property))
+		}
 	}
 
-	return nil
+	return pointer.String()
 }
 
 // loadFileAsReferenceLoader converts a file path to a gojsonschema JSONLoader with error handling.
@@ -77,3 +102,307 @@ func loadFileAsReferenceLoader(path string) (gojsonschema.JSONLoader, error) {
 
 	return gojsonschema.NewReferenceLoader("file:///" + filepath.ToSlash(abs)), nil
 }
+
+// validateDefinitions runs the checks that the JSON Schema cannot express on
+// the merged definitions. Every violation is reported, positioned at the
+// offending member of the file the definition was loaded from.
+func validateDefinitions(defs map[string]definition, requiredLocales []string) error {
+	return errors.Join(
+		validateConsistency(defs),
+		validatePlaceholders(defs),
+		validateHTTPStatus(defs),
+		validateTranslations(defs, requiredLocales),
+		validateRetry(defs),
+		validateDeprecations(defs),
+	)
+}
+
+// codeErrorf returns a diagnostic for a violation of rule by the definition
+// with the given code, positioned at loc when it was loaded from a file.
+func codeErrorf(loc location, rule, code, format string, args ...any) error {
+	d := loc.errorf(rule, format, args...)
+	d.Code = code
+	return d
+}
+
+// validateConsistency checks the fields of each definition against its key
+// and against the other definitions: the code must equal the key, codes
+// sharing a prefix must share a domain and vice versa, and no two active
+// definitions may use the same msg. Each conflicting prefix/domain pair is
+// reported once, at the first code using it.
+func validateConsistency(defs map[string]definition) error {
+	var errs []error
+
+	domainOf := make(map[string]string) // prefix -> first code using it
+	prefixOf := make(map[string]string) // domain -> first code using it
+	msgOf := make(map[string]string)    // normalized msg -> first code using it
+	reported := make(map[[2]string]bool)
+	for _, code := range sortedCodes(defs) {
+		def := defs[code]
+		if def.Code != code {
+			errs = append(errs, codeErrorf(def.loc.field("code"), RuleKeyMismatch, code, "key does not match code %q", def.Code))
+		}
+
+		prefix := code[:min(2, len(code))]
+		pair := [2]string{prefix, def.Domain}
+		if first, ok := domainOf[prefix]; !ok {
+			domainOf[prefix] = code
+		} else if other := defs[first].Domain; other != def.Domain && !reported[pair] {
+			reported[pair] = true
+			errs = append(errs, codeErrorf(def.loc.field("domain"), RulePrefixDomain, code, "domain %q differs from domain %q of %s with the same prefix", def.Domain, other, first))
+		}
+		if first, ok := prefixOf[def.Domain]; !ok {
+			prefixOf[def.Domain] = code
+		} else if other := first[:min(2, len(first))]; other != prefix && !reported[pair] {
+			reported[pair] = true
+			errs = append(errs, codeErrorf(def.loc.field("domain"), RulePrefixDomain, code, "domain %q is already used with prefix %q by %s", def.Domain, other, first))
+		}
+
+		if def.Deprecated {
+			continue
+		}
+		msg := strings.ToLower(strings.TrimSpace(def.Msg))
+		if first, ok := msgOf[msg]; ok {
+			errs = append(errs, codeErrorf(def.loc.field("msg"), RuleDuplicateMsg, code, "msg %q duplicates the msg of %s", def.Msg, first))
+			continue
+		}
+		msgOf[msg] = code
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateRetry checks that retry hints are only declared on retryable
+// definitions and that the backoff bounds are ordered.
+func validateRetry(defs map[string]definition) error {
+	var errs []error
+	for _, code := range sortedCodes(defs) {
+		def := defs[code]
+		if def.Retry == nil {
+			continue
+		}
+		if !def.Retryable {
+			errs = append(errs, codeErrorf(def.loc.field("retry"), RuleRetry, code, "retry hints declared but retryable is not true"))
+		}
+		if def.Retry.MaxBackoffMS != 0 && def.Retry.MaxBackoffMS < def.Retry.BackoffMS {
+			errs = append(errs, codeErrorf(def.loc.field("retry").field("max_backoff_ms"), RuleRetry, code, "max_backoff_ms %d is less than backoff_ms %d", def.Retry.MaxBackoffMS, def.Retry.BackoffMS))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateDeprecations checks that replaced_by is only set on deprecated
+// definitions and points to another existing, non-deprecated code.
+func validateDeprecations(defs map[string]definition) error {
+	var errs []error
+	for _, code := range sortedCodes(defs) {
+		def := defs[code]
+		if def.ReplacedBy == "" {
+			continue
+		}
+		if !def.Deprecated {
+			errs = append(errs, codeErrorf(def.loc.field("replaced_by"), RuleDeprecation, code, "replaced_by is set but deprecated is not true"))
+		}
+
+		replacement, ok := defs[def.ReplacedBy]
+		switch {
+		case def.ReplacedBy == code:
+			errs = append(errs, codeErrorf(def.loc.field("replaced_by"), RuleDeprecation, code, "replaced_by refers to itself"))
+		case !ok:
+			errs = append(errs, codeErrorf(def.loc.field("replaced_by"), RuleDeprecation, code, "replaced_by %q does not exist", def.ReplacedBy))
+		case replacement.Deprecated:
+			errs = append(errs, codeErrorf(def.loc.field("replaced_by"), RuleDeprecation, code, "replaced_by %q is itself deprecated", def.ReplacedBy))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateDomains checks every definition and retired code against the domain
+// registry: its prefix must be registered and its domain must be the one
+// registered for that prefix. Each domain may only be registered once.
+func validateDomains(defs map[string]definition, retired map[string]tombstone, domains map[string]domainEntry) error {
+	var errs []error
+
+	prefixes := make([]string, 0, len(domains))
+	for prefix := range domains {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	owners := make(map[string]string, len(domains))
+	for _, prefix := range prefixes {
+		domain := domains[prefix].Domain
+		if other, ok := owners[domain]; ok {
+			errs = append(errs, domains[prefix].loc.field("domain").errorf(RuleDomainRegistry, "domain %q is registered under both %q and %q", domain, other, prefix))
+			continue
+		}
+		owners[domain] = prefix
+	}
+
+	check := func(code string, loc location, domain string) {
+		prefix := code[:min(2, len(code))]
+		entry, ok := domains[prefix]
+		switch {
+		case !ok:
+			errs = append(errs, codeErrorf(loc, RuleDomainRegistry, code, "prefix %q is not registered in the domain registry", prefix))
+		case domain != entry.Domain:
+			errs = append(errs, codeErrorf(loc.field("domain"), RuleDomainRegistry, code, "domain %q does not match %q registered for prefix %q", domain, entry.Domain, prefix))
+		}
+	}
+
+	for _, code := range sortedCodes(defs) {
+		check(code, defs[code].loc, defs[code].Domain)
+	}
+
+	retiredCodes := make([]string, 0, len(retired))
+	for code := range retired {
+		retiredCodes = append(retiredCodes, code)
+	}
+	sort.Strings(retiredCodes)
+	for _, code := range retiredCodes {
+		check(code, retired[code].loc, retired[code].Domain)
+	}
+
+	return errors.Join(errs...)
+}
+
+// sortedCodes returns the codes of defs in alphabetical order.
+func sortedCodes(defs map[string]definition) []string {
+	codes := make([]string, 0, len(defs))
+	for code := range defs {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+// validateTranslations checks that every definition translates msg into each
+// required locale and that no locale is declared twice with different casing.
+func validateTranslations(defs map[string]definition, requiredLocales []string) error {
+	var errs []error
+	for _, code := range sortedCodes(defs) {
+		def := defs[code]
+
+		seen := make(map[string]bool, len(def.Translations))
+		for _, tag := range sortedKeys(def.Translations) {
+			key := strings.ToLower(tag)
+			if seen[key] {
+				errs = append(errs, codeErrorf(def.loc.field("translations").field(tag), RuleTranslation, code, "locale %q is declared more than once", tag))
+			}
+			seen[key] = true
+		}
+
+		for _, tag := range requiredLocales {
+			if !seen[strings.ToLower(tag)] {
+				errs = append(errs, codeErrorf(def.loc.field("translations"), RuleTranslation, code, "missing translation for required locale %q", tag))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sortedKeys returns the keys of m in alphabetical order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// validateHTTPStatus checks that every declared http_status is a status code
+// known to net/http.
+func validateHTTPStatus(defs map[string]definition) error {
+	var errs []error
+	for _, code := range sortedCodes(defs) {
+		def := defs[code]
+		status := def.HTTPStatus
+		if status != 0 && http.StatusText(status) == "" {
+			errs = append(errs, codeErrorf(def.loc.field("http_status"), RuleHTTPStatus, code, "http_status %d is not a known HTTP status code", status))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// placeholderPattern matches {name} placeholders in msg, cause and translations.
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// reservedParamNames are identifiers the generated New<CODE> and
+// New<CODE>Context constructors use themselves, so a param with one of these
+// names would shadow them and the generated code would not compile. The
+// constructor body also refers to the code itself, which validatePlaceholders
+// rejects separately.
+var reservedParamNames = map[string]bool{
+	"ctx":     true,
+	"context": true,
+}
+
+// validatePlaceholders checks that every {placeholder} used in msg, cause or a
+// translation is declared in params, reporting it at the member it appears in,
+// that every param name is usable as a Go identifier that the generated
+// constructors do not already use, and that every param is used somewhere.
+// All violations are reported together, ordered by error code.
+func validatePlaceholders(defs map[string]definition) error {
+	var errs []error
+	for _, code := range sortedCodes(defs) {
+		def := defs[code]
+
+		declared := make(map[string]bool, len(def.Params))
+		for i, p := range def.Params {
+			loc := def.loc.field("params").field(strconv.Itoa(i))
+			switch {
+			case !token.IsIdentifier(p.Name) || p.Name == "_":
+				errs = append(errs, codeErrorf(loc, RuleParam, code, "param %q is not a valid Go identifier", p.Name))
+			case reservedParamNames[p.Name] || p.Name == code:
+				errs = append(errs, codeErrorf(loc, RuleParam, code, "param %q is reserved by the generated constructors", p.Name))
+			}
+			if declared[p.Name] {
+				errs = append(errs, codeErrorf(loc, RuleParam, code, "param %q is declared more than once", p.Name))
+			}
+			declared[p.Name] = true
+		}
+
+		type member struct {
+			loc  location
+			text string
+		}
+		members := []member{
+			{def.loc.field("msg"), def.Msg},
+			{def.loc.field("cause"), def.Cause},
+		}
+		for _, tag := range sortedKeys(def.Translations) {
+			members = append(members, member{def.loc.field("translations").field(tag), def.Translations[tag]})
+		}
+
+		used := make(map[string]bool)
+		for _, m := range members {
+			reported := make(map[string]bool)
+			for _, match := range placeholderPattern.FindAllStringSubmatch(m.text, -1) {
+				name := match[1]
+				used[name] = true
+				if declared[name] || reported[name] {
+					continue
+				}
+				reported[name] = true
+				errs = append(errs, codeErrorf(m.loc, RulePlaceholder, code, "placeholder {%s} is not declared in params", name))
+			}
+		}
+
+		for i, p := range def.Params {
+			if !used[p.Name] {
+				used[p.Name] = true
+				loc := def.loc.field("params").field(strconv.Itoa(i))
+				errs = append(errs, codeErrorf(loc, RuleParam, code, "param %q is not used in msg, cause or any translation", p.Name))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,94 +1,398 @@
 package errz
 
 import (
+	"io/fs"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xeipuuv/gojsonschema"
 )
 
-func TestValidateJSON_Valid(t *testing.T) {
+// validateFile validates the file at path against the schema at schemaPath,
+// the way the pipeline does.
+func validateFile(t *testing.T, schemaPath, path string) error {
+	t.Helper()
+	schema, err := compileSchema(schemaPath)
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	return validateDocument(schema, newSourceFile(path, content))
+}
+
+// mustCompileSchema compiles the schema at path, failing t if it cannot.
+func mustCompileSchema(t *testing.T, path string) *gojsonschema.Schema {
+	t.Helper()
+	schema, err := compileSchema(path)
+	require.NoError(t, err)
+	return schema
+}
+
+func TestValidateDocument_Valid(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	validFile := "testdata/valid/common.json"
 
-	err := validateJSON(schema, validFile)
+	err := validateFile(t, schema, validFile)
 	assert.NoError(t, err)
 }
 
-func TestValidateJSON_Invalid(t *testing.T) {
+func TestValidateDocument_Invalid(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	invalidFile := "testdata/invalid/invalid_missing_required.json"
 
-	err := validateJSON(schema, invalidFile)
+	err := validateFile(t, schema, invalidFile)
 	assert.Error(t, err)
 	assert.True(t, strings.Contains(err.Error(), "JSON validation failed"))
 }
 
-func TestValidateJSON_FileNotFound(t *testing.T) {
-	schema := "testdata/error_schema.json"
+func TestLoadDefinitionsFile_FileNotFound(t *testing.T) {
+	schema := mustCompileSchema(t, "testdata/error_schema.json")
 	missingFile := "testdata/missing.json"
 
-	err := validateJSON(schema, missingFile)
+	_, _, err := loadDefinitionsFile(missingFile, schema)
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "file not found")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	var d *Diagnostic
+	require.ErrorAs(t, err, &d)
+	assert.Equal(t, RuleRead, d.Rule)
+	assert.Equal(t, missingFile, d.File)
 }
 
-func TestValidateJSON_InvalidSchema(t *testing.T) {
-	schema := "testdata/invalid_schema.json" // malformed schema
-	validFile := "testdata/valid/common.json"
+func TestGenerator_Validate_InvalidSchema(t *testing.T) {
+	g := Generator{
+		SchemaPath:     "testdata/invalid_schema.json", // malformed schema
+		DefinitionsDir: "testdata/inconsistent",
+	}
 
-	err := validateJSON(schema, validFile)
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failed to run validation")
+	ds := g.Validate()
+	assert.Contains(t, ds.Error(), "cannot compile schema")
+	// The definitions are still loaded and checked.
+	assert.Contains(t, ds.Error(), "key does not match")
+}
+
+func TestCompileSchema(t *testing.T) {
+	schema, err := compileSchema("testdata/error_schema.json")
+	require.NoError(t, err)
+
+	src := newSourceFile("x.json", []byte(`{"PM0001": {"domain": "payment", "code": "PM0001", "msg": "m"}}`))
+	assert.ErrorContains(t, validateDocument(schema, src), "x.json:1:2: PM0001: cause is required")
+
+	_, err = compileSchema("testdata/invalid_schema.json")
+	assert.ErrorContains(t, err, "cannot compile schema")
+	_, err = compileSchema("testdata/missing.json")
+	assert.ErrorContains(t, err, "file not found")
 }
 
-func TestValidateAllJSONFiles_AllValid(t *testing.T) {
+func TestLoadAndValidateDefinitions_SchemaAllValid(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	dir := "testdata/valid"
 
-	err := validateAllJSONFiles(schema, dir)
+	_, _, err := loadAndValidateDefinitions(dir, mustCompileSchema(t, schema), 0)
 	assert.NoError(t, err)
 }
 
-func TestValidateAllJSONFiles_HasInvalid(t *testing.T) {
+func TestLoadAndValidateDefinitions_SchemaHasInvalid(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	dir := "testdata/mixed" // includes both valid and invalid JSONs
 
-	err := validateAllJSONFiles(schema, dir)
+	_, _, err := loadAndValidateDefinitions(dir, mustCompileSchema(t, schema), 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "validation failed for")
 }
 
-func TestValidateAllJSONFiles_DirNotFound(t *testing.T) {
+func TestLoadAndValidateDefinitions_SchemaDirNotFound(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	dir := "testdata/notfound"
 
-	err := validateAllJSONFiles(schema, dir)
+	_, _, err := loadAndValidateDefinitions(dir, mustCompileSchema(t, schema), 0)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to read directory")
 }
 
-func TestValidateAllJSONFiles_SkipNonJSON(t *testing.T) {
+func TestLoadAndValidateDefinitions_SchemaSkipNonJSON(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	dir := "testdata/mixed_with_nonjson"
 
-	err := validateAllJSONFiles(schema, dir)
+	_, _, err := loadAndValidateDefinitions(dir, mustCompileSchema(t, schema), 0)
 	assert.NoError(t, err)
 }
 
-func TestValidateAllJSONFiles_EmptyDirectory(t *testing.T) {
+func TestLoadAndValidateDefinitions_SchemaEmptyDirectory(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	dir := "testdata/empty"
 
-	err := validateAllJSONFiles(schema, dir)
+	_, _, err := loadAndValidateDefinitions(dir, mustCompileSchema(t, schema), 0)
 	assert.Error(t, err)
 }
 
-func TestValidateJSON_ExtraFields(t *testing.T) {
+func TestValidateDocument_ExtraFields(t *testing.T) {
 	schema := "testdata/error_schema.json"
 	file := "testdata/invalid/invalid_extra_field.json"
 
-	err := validateJSON(schema, file)
+	err := validateFile(t, schema, file)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "Additional property")
 }
+
+func TestValidateDocument_Params(t *testing.T) {
+	schema := "testdata/error_schema.json"
+
+	err := validateFile(t, schema, "testdata/params/payment.json")
+	assert.NoError(t, err)
+
+	err = validateFile(t, schema, "testdata/invalid/invalid_param_type.json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "params.0.type")
+}
+
+func TestValidatePlaceholders_Declared(t *testing.T) {
+	defs, _, err := loadAndValidateDefinitions("testdata/params", nil, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, validatePlaceholders(defs))
+}
+
+func TestValidatePlaceholders_Undeclared(t *testing.T) {
+	err := validatePlaceholders(map[string]definition{
+		"PM0001": {
+			Code:   "PM0001",
+			Msg:    "need {required}, have {available}",
+			Cause:  "missing {required}",
+			Params: []param{{Name: "available", Type: "float64"}},
+		},
+	})
+	assert.EqualError(t, err, "PM0001: placeholder {required} is not declared in params\n"+
+		"PM0001: placeholder {required} is not declared in params")
+}
+
+func TestValidatePlaceholders_UndeclaredIsPositioned(t *testing.T) {
+	src := newSourceFile("x.json", []byte(`{
+  "PM0001": {
+    "msg": "need {required}",
+    "cause": "short",
+    "translations": { "th": "ต้องการ {amount}" }
+  }
+}`))
+	defs, _, err := decodeDefinitions(src)
+	require.NoError(t, err)
+
+	diags := newDiagnostics(validatePlaceholders(defs))
+	require.Len(t, diags, 2)
+	assert.Equal(t, "msg", diags[0].Field)
+	assert.Equal(t, Position{Line: 3, Column: 5}, diags[0].Position)
+	assert.Equal(t, "translations.th", diags[1].Field)
+	assert.Equal(t, Position{Line: 5, Column: 23}, diags[1].Position)
+	for _, d := range diags {
+		assert.Equal(t, "PM0001", d.Code)
+		assert.Equal(t, RulePlaceholder, d.Rule)
+	}
+}
+
+func TestValidatePlaceholders_InvalidParamName(t *testing.T) {
+	err := validatePlaceholders(map[string]definition{
+		"PM0001": {
+			Code:   "PM0001",
+			Msg:    "{type}",
+			Params: []param{{Name: "type", Type: "string"}, {Name: "type", Type: "string"}},
+		},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `param "type" is not a valid Go identifier`)
+	assert.Contains(t, err.Error(), `param "type" is declared more than once`)
+}
+
+func TestValidatePlaceholders_UnusedParam(t *testing.T) {
+	err := validatePlaceholders(map[string]definition{
+		"PM0001": {
+			Code:         "PM0001",
+			Msg:          "need {required}",
+			Cause:        "for {user}",
+			Translations: map[string]string{"th": "ต้องการ {required} {currency}"},
+			Params: []param{
+				{Name: "required", Type: "float64"},
+				{Name: "user", Type: "string"},
+				{Name: "currency", Type: "string"},
+				{Name: "available", Type: "float64"},
+			},
+		},
+	})
+	assert.EqualError(t, err, `PM0001: param "available" is not used in msg, cause or any translation`)
+
+	var d *Diagnostic
+	require.ErrorAs(t, err, &d)
+	assert.Equal(t, RuleParam, d.Rule)
+}
+
+func TestValidatePlaceholders_ReservedParamName(t *testing.T) {
+	err := validatePlaceholders(map[string]definition{
+		"PM0001": {
+			Code:   "PM0001",
+			Msg:    "{ctx} {context} {PM0001}",
+			Params: []param{{Name: "ctx", Type: "string"}, {Name: "context", Type: "string"}, {Name: "PM0001", Type: "string"}},
+		},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `param "ctx" is reserved by the generated constructors`)
+	assert.Contains(t, err.Error(), `param "context" is reserved by the generated constructors`)
+	assert.Contains(t, err.Error(), `param "PM0001" is reserved by the generated constructors`)
+}
+
+func TestValidateHTTPStatus(t *testing.T) {
+	err := validateHTTPStatus(map[string]definition{
+		"CM0404": {Code: "CM0404", HTTPStatus: 404},
+		"CM0000": {Code: "CM0000"},
+		"XX0001": {Code: "XX0001", HTTPStatus: 499},
+	})
+	assert.EqualError(t, err, `XX0001: http_status 499 is not a known HTTP status code`)
+}
+
+func TestValidateDocument_HTTPStatusOutOfRange(t *testing.T) {
+	err := validateFile(t, "testdata/error_schema.json", "testdata/invalid/invalid_http_status.json")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "http_status")
+}
+
+func TestValidateTranslations(t *testing.T) {
+	defs := map[string]definition{
+		"PM0001": {Code: "PM0001", Translations: map[string]string{"th": "ยอดเงินไม่พอ", "TH": "ซ้ำ"}},
+		"PM0002": {Code: "PM0002", Translations: map[string]string{"en-GB": "timeout"}},
+	}
+
+	err := validateTranslations(defs, []string{"th"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `PM0001: locale "th" is declared more than once`)
+	assert.Contains(t, err.Error(), `PM0002: missing translation for required locale "th"`)
+
+	assert.NoError(t, validateTranslations(map[string]definition{}, []string{"th"}))
+}
+
+func TestValidatePlaceholders_Translations(t *testing.T) {
+	err := validatePlaceholders(map[string]definition{
+		"PM0001": {Code: "PM0001", Msg: "short", Translations: map[string]string{"th": "ขาด {amount}"}},
+	})
+	assert.EqualError(t, err, `PM0001: placeholder {amount} is not declared in params`)
+}
+
+func TestValidateRetry(t *testing.T) {
+	err := validateRetry(map[string]definition{
+		"PM0001": {Code: "PM0001", Retry: &retryHints{MaxAttempts: 2}},
+		"PM0002": {Code: "PM0002", Retryable: true, Retry: &retryHints{BackoffMS: 500, MaxBackoffMS: 100}},
+		"PM0003": {Code: "PM0003", Retryable: true, Retry: &retryHints{MaxAttempts: 3, BackoffMS: 100, MaxBackoffMS: 500}},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `PM0001: retry hints declared but retryable is not true`)
+	assert.Contains(t, err.Error(), `PM0002: max_backoff_ms 100 is less than backoff_ms 500`)
+	assert.NotContains(t, err.Error(), "PM0003")
+}
+
+func TestValidateDeprecations(t *testing.T) {
+	err := validateDeprecations(map[string]definition{
+		"PM0001": {Code: "PM0001", Deprecated: true, ReplacedBy: "PM0004"},
+		"PM0002": {Code: "PM0002", ReplacedBy: "PM0004"},
+		"PM0003": {Code: "PM0003", Deprecated: true, ReplacedBy: "PM0003"},
+		"PM0004": {Code: "PM0004"},
+		"PM0005": {Code: "PM0005", Deprecated: true, ReplacedBy: "PM0009"},
+		"PM0006": {Code: "PM0006", Deprecated: true, ReplacedBy: "PM0001"},
+		"PM0007": {Code: "PM0007", Deprecated: true},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `PM0002: replaced_by is set but deprecated is not true`)
+	assert.Contains(t, err.Error(), `PM0003: replaced_by refers to itself`)
+	assert.Contains(t, err.Error(), `PM0005: replaced_by "PM0009" does not exist`)
+	assert.Contains(t, err.Error(), `PM0006: replaced_by "PM0001" is itself deprecated`)
+	assert.NotContains(t, err.Error(), `"PM0001":`)
+	assert.NotContains(t, err.Error(), "PM0007")
+}
+
+func TestValidateDocument_Retired(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/retired", mustCompileSchema(t, "testdata/error_schema.json"), 0)
+	assert.NoError(t, err)
+}
+
+func TestValidateDocument_Domains(t *testing.T) {
+	_, err := loadDomains("testdata/domains.json", mustCompileSchema(t, "testdata/domains_schema.json"))
+	assert.NoError(t, err)
+	_, err = loadDomains("domains.json", mustCompileSchema(t, "schema/domains_schema.json"))
+	assert.NoError(t, err)
+}
+
+func TestValidateDomains(t *testing.T) {
+	domains := map[string]domainEntry{
+		"AU": {Domain: "auth", DisplayName: "Auth"},
+		"PM": {Domain: "payment", DisplayName: "Payment"},
+		"PY": {Domain: "payment", DisplayName: "Payment"},
+	}
+	err := validateDomains(map[string]definition{
+		"AU0001": {Code: "AU0001", Domain: "auth"},
+		"PM0009": {Code: "PM0009", Domain: "auth"},
+		"ZZ0001": {Code: "ZZ0001", Domain: "zz"},
+	}, map[string]tombstone{
+		"AU0002": {Code: "AU0002", Domain: "payment"},
+	}, domains)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `domain "payment" is registered under both "PM" and "PY"`)
+	assert.Contains(t, err.Error(), `PM0009: domain "auth" does not match "payment" registered for prefix "PM"`)
+	assert.Contains(t, err.Error(), `ZZ0001: prefix "ZZ" is not registered in the domain registry`)
+	assert.Contains(t, err.Error(), `AU0002: domain "payment" does not match "auth" registered for prefix "AU"`)
+	assert.NotContains(t, err.Error(), "AU0001")
+}
+
+func TestValidateDomains_DuplicateIsPositioned(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+  "PM": { "domain": "payment", "display_name": "Payment" },
+  "PY": { "domain": "payment", "display_name": "Payment" }
+}`), 0644))
+	domains, err := loadDomains(path, nil)
+	require.NoError(t, err)
+
+	var d *Diagnostic
+	require.ErrorAs(t, validateDomains(nil, nil, domains), &d)
+	assert.Equal(t, path, d.File)
+	assert.Equal(t, Position{Line: 3, Column: 11}, d.Position)
+	assert.Equal(t, RuleDomainRegistry, d.Rule)
+	assert.Empty(t, d.Code)
+	assert.Equal(t, "PY.domain", d.Field)
+}
+
+func TestValidateConsistency(t *testing.T) {
+	err := validateConsistency(map[string]definition{
+		"AU0001": {Code: "AU0001", Domain: "auth", Msg: "a"},
+		"AU0002": {Code: "AU0002", Domain: "payment", Msg: "b"},
+		"PM0001": {Code: "PM0001", Domain: "payment", Msg: "c"},
+		"PM0002": {Code: "PM0003", Domain: "payment", Msg: "d"},
+		"PM0004": {Code: "PM0004", Domain: "payment", Msg: " C "},
+		"PM0005": {Code: "PM0005", Domain: "payment", Msg: "c", Deprecated: true},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `AU0002: domain "payment" differs from domain "auth" of AU0001 with the same prefix`)
+	assert.Contains(t, err.Error(), `PM0001: domain "payment" is already used with prefix "AU" by AU0002`)
+	assert.Contains(t, err.Error(), `PM0002: key does not match code "PM0003"`)
+	assert.Contains(t, err.Error(), `PM0004: msg " C " duplicates the msg of PM0001`)
+	assert.NotContains(t, err.Error(), "PM0005")
+}
+
+func TestValidateDefinitions_ReportsFiles(t *testing.T) {
+	defs, _, err := loadAndValidateDefinitions("testdata/inconsistent", nil, 0)
+	require.NoError(t, err)
+
+	err = validateDefinitions(defs, nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `testdata/inconsistent/payment.json:4:5: PM0001: key does not match code "PM0002"
+  4 |     "code": "PM0002",
+    |     ^`)
+	assert.Contains(t, err.Error(), `testdata/inconsistent/wallet.json:3:5: PM0003: domain "wallet" differs from domain "payment" of PM0001 with the same prefix`)
+	assert.Contains(t, err.Error(), `testdata/inconsistent/wallet.json:5:5: PM0003: msg "Insufficient balance" duplicates the msg of PM0001`)
+}
+
+func TestLoadAndValidateDefinitions_SchemaReportsEveryFile(t *testing.T) {
+	_, _, err := loadAndValidateDefinitions("testdata/broken", mustCompileSchema(t, "testdata/error_schema.json"), 0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "validation failed for common.json")
+	assert.Contains(t, err.Error(), "unmarshal error at testdata/broken/payment.json")
+	assert.NotContains(t, err.Error(), "auth.json")
+}